@@ -2,12 +2,15 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
 	"github.com/pHo9UBenaA/cmdbook/internal/handler"
 )
 
@@ -22,14 +25,32 @@ func main() {
 		Short: "Command Book - Manage your frequently used commands",
 	}
 
-	rootCmd.CompletionOptions.HiddenDefaultCmd = true
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
 
 	rootCmd.AddCommand(
 		addCmd(),
 		updateCmd(),
 		execCmd(),
+		runCmd(),
 		removeCmd(),
+		moveCmd(),
+		copyCmd(),
 		listCmd(),
+		pickCmd(),
+		initCmd(),
+		completionCmd(),
+		syncCmd(),
+		mergeCmd(),
+		diffCmd(),
+		remoteCmd(),
+		shimCmd(),
+		keysCmd(),
+		historyCmd(),
+		outputCmd(),
+		statsCmd(),
+		topCmd(),
+		pruneCmd(),
+		dedupeCmd(),
 	)
 
 	if err := rootCmd.Execute(); err != nil {
@@ -38,25 +59,39 @@ func main() {
 }
 
 func addCmd() *cobra.Command {
-	var short, prefix string
+	var short, prefix, file string
+	var opts handler.AddOptions
 
 	const commandIndex = 0
 
 	cmd := &cobra.Command{
-		Use:     "add <command>",
+		Use:     "add [command]",
 		Aliases: []string{"a"},
 		Short:   "Add a new command",
-		Args:    cobra.ExactArgs(1),
+		Args:    cobra.RangeArgs(0, 1),
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := handler.AddCommand(configPath, prefix, short, args[commandIndex]); err != nil {
+			command, err := readCommand(args, commandIndex, file, len(opts.Steps) > 0)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+
+			if err := handler.AddCommand(configPath, prefix, short, command, opts); err != nil {
 				fmt.Println("Error:", err)
 				os.Exit(1)
 			}
 		},
 	}
 
-	cmd.Flags().StringVarP(&short, "short", "S", "", "Short command name")
+	cmd.Flags().StringVarP(&short, "short", "S", "", "Short command name (generated from the command when omitted)")
 	cmd.Flags().StringVarP(&prefix, "prefix", "P", "", "Command prefix")
+	cmd.Flags().BoolVarP(&opts.Yes, "yes", "y", false, "Save a generated short name without confirming it")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Replace an existing command with the same prefix and short name")
+	cmd.Flags().StringVarP(&opts.Dir, "dir", "d", "", "Working directory to run the command in")
+	cmd.Flags().StringArrayVarP(&opts.Env, "env", "e", nil, "Environment variable to set (KEY=VALUE, repeatable)")
+	cmd.Flags().StringVar(&opts.Shell, "shell", "", "Shell or interpreter to run the command with (e.g. bash, \"python3 -c\")")
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Read the command from a script file ('-' for stdin)")
+	cmd.Flags().StringArrayVar(&opts.Steps, "step", nil, "Make a workflow running prefix:short[@succeeded|failed|always] (repeatable)")
 
 	cmd.RegisterFlagCompletionFunc("prefix", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return getPrefixes(), cobra.ShellCompDirectiveNoFileComp
@@ -66,7 +101,8 @@ func addCmd() *cobra.Command {
 }
 
 func updateCmd() *cobra.Command {
-	var newPrefix, newShort, newCommand string
+	var newPrefix, newShort, newCommand, dir, shell string
+	var opts handler.UpdateOptions
 
 	const (
 		oldPrefixIndex   = 0
@@ -88,7 +124,15 @@ func updateCmd() *cobra.Command {
 				os.Exit(1)
 			}
 
-			if err := handler.UpdateCommand(configPath, oldPrefix, oldShort, newPrefix, newShort, newCommand); err != nil {
+			if cmd.Flags().Changed("dir") {
+				opts.Dir = &dir
+			}
+
+			if cmd.Flags().Changed("shell") {
+				opts.Shell = &shell
+			}
+
+			if err := handler.UpdateCommand(configPath, oldPrefix, oldShort, newPrefix, newShort, newCommand, opts); err != nil {
 				fmt.Println("Error:", err)
 				os.Exit(1)
 			}
@@ -98,6 +142,14 @@ func updateCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&newPrefix, "new-prefix", "P", "", "New prefix for the command")
 	cmd.Flags().StringVarP(&newShort, "new-short", "S", "", "New short name for the command")
 	cmd.Flags().StringVarP(&newCommand, "new-command", "C", "", "New command content")
+	cmd.Flags().StringVarP(&dir, "dir", "d", "", "Working directory to run the command in (empty to clear)")
+	cmd.Flags().StringArrayVarP(&opts.Env, "env", "e", nil, "Environment variable to set (KEY=VALUE, repeatable)")
+	cmd.Flags().StringArrayVar(&opts.UnsetEnv, "unset-env", nil, "Environment variable to remove (repeatable)")
+	cmd.Flags().StringVar(&shell, "shell", "", "Shell or interpreter to run the command with (empty for the default)")
+	cmd.Flags().StringArrayVar(&opts.Steps, "step", nil, "Replace the steps of a workflow (repeatable)")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Replace a command already at the new prefix and short name")
+	cmd.Flags().BoolVar(&opts.Swap, "swap", false, "Exchange with the command at the new prefix and short name")
+	cmd.MarkFlagsMutuallyExclusive("force", "swap")
 
 	cmd.RegisterFlagCompletionFunc("new-prefix", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return getPrefixes(), cobra.ShellCompDirectiveNoFileComp
@@ -108,7 +160,7 @@ func updateCmd() *cobra.Command {
 			return getPrefixes(), cobra.ShellCompDirectiveNoFileComp
 		}
 		if len(args) == oldShortCmdIndex {
-			return getShorts(args[oldPrefixIndex]), cobra.ShellCompDirectiveNoFileComp
+			return getShorts(args[oldPrefixIndex]), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
 		}
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
@@ -117,6 +169,8 @@ func updateCmd() *cobra.Command {
 }
 
 func execCmd() *cobra.Command {
+	var opts handler.RunOptions
+
 	const (
 		prefixIndex   = 0
 		shortCmdIndex = 1
@@ -124,24 +178,203 @@ func execCmd() *cobra.Command {
 	)
 
 	cmd := &cobra.Command{
-		Use:     "exec <prefix> <short-cmd>",
+		Use:     "exec [<prefix>] <short-cmd> [<prefix> <short-cmd>...] [-- <args>...]",
 		Aliases: []string{"e"},
-		Short:   "Execute a command",
-		Args:    cobra.ExactArgs(argsNum),
+		Short:   "Execute a command, or several in sequence",
+		Args: func(cmd *cobra.Command, args []string) error {
+			args, _ = splitAtDash(cmd, args)
+			if len(args) != 1 && (len(args) < argsNum || len(args)%argsNum != 0) {
+				return fmt.Errorf("expects <short-cmd> or <prefix> <short-cmd> pairs, received %d args", len(args))
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			args, opts.Args = splitAtDash(cmd, args)
+
+			var err error
+			switch len(args) {
+			case 1:
+				err = handler.ExecCommand(configPath, "", args[0], opts.ExecOptions)
+			case argsNum:
+				err = handler.ExecCommand(configPath, args[prefixIndex], args[shortCmdIndex], opts.ExecOptions)
+			default:
+				var refs []domain.Ref
+				for i := 0; i < len(args); i += argsNum {
+					refs = append(refs, domain.Ref{Prefix: args[i+prefixIndex], Short: args[i+shortCmdIndex]})
+				}
+				err = handler.RunCommands(configPath, refs, opts)
+			}
+
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	addExecFlags(cmd, &opts.ExecOptions)
+	cmd.Flags().BoolVar(&opts.ContinueOnError, "continue-on-error", false, "Keep running the remaining commands after a failure")
+	cmd.Flags().StringVar(&opts.From, "from", "", "Resume a workflow at this step (number or prefix:short)")
+
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		switch {
+		case cmd.ArgsLenAtDash() >= 0:
+			return nil, cobra.ShellCompDirectiveDefault
+		case len(args) == 0:
+			return getExecNames(), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+		case len(args)%argsNum == prefixIndex:
+			return getExecPrefixes(), cobra.ShellCompDirectiveNoFileComp
+		default:
+			return getExecShorts(args[len(args)-1]), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+		}
+	}
+	_ = cmd.RegisterFlagCompletionFunc("set", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		args, _ = splitAtDash(cmd, args)
+		return getTemplateValues(execArgRefs(args), toComplete)
+	})
+
+	return cmd
+}
+
+// splitAtDash separates the arguments given after -- from the others.
+func splitAtDash(cmd *cobra.Command, args []string) ([]string, []string) {
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 {
+		return args, nil
+	}
+	return args[:dash], args[dash:]
+}
+
+func runCmd() *cobra.Command {
+	var opts handler.RunOptions
+
+	cmd := &cobra.Command{
+		Use:   "run <prefix:short>...",
+		Short: "Run several commands in sequence or in parallel",
+		Args:  cobra.MinimumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := handler.ExecCommand(configPath, args[prefixIndex], args[shortCmdIndex]); err != nil {
+			refs := make([]domain.Ref, 0, len(args))
+			for _, arg := range args {
+				ref, err := domain.ParseRef(arg)
+				if err != nil {
+					fmt.Println("Error:", err)
+					os.Exit(1)
+				}
+				refs = append(refs, ref)
+			}
+
+			if err := handler.RunCommands(configPath, refs, opts); err != nil {
 				fmt.Println("Error:", err)
 				os.Exit(1)
 			}
 		},
 	}
 
+	addExecFlags(cmd, &opts.ExecOptions)
+	cmd.Flags().BoolVarP(&opts.Parallel, "parallel", "p", false, "Run every command at once")
+	cmd.Flags().BoolVar(&opts.ContinueOnError, "continue-on-error", false, "Keep running the remaining commands after a failure")
+
 	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return getExecRefs(), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+	}
+	_ = cmd.RegisterFlagCompletionFunc("set", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var refs []domain.Ref
+		for _, arg := range args {
+			if ref, err := domain.ParseRef(arg); err == nil {
+				refs = append(refs, ref)
+			}
+		}
+		return getTemplateValues(refs, toComplete)
+	})
+
+	return cmd
+}
+
+func addExecFlags(cmd *cobra.Command, opts *handler.ExecOptions) {
+	cmd.Flags().BoolVarP(&opts.Yes, "yes", "y", false, "Run high-risk commands without confirmation")
+	cmd.Flags().BoolVar(&opts.Here, "here", false, "Run in the current directory instead of the stored one")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 0, "Kill the command after this duration (e.g. 30s)")
+	cmd.Flags().IntVar(&opts.Retry, "retry", 0, "Retry a failed command up to this many times")
+	cmd.Flags().DurationVar(&opts.RetryDelay, "retry-delay", 0, "Wait this long between retries (e.g. 5s)")
+	cmd.Flags().StringSliceVar(&opts.RetryOn, "retry-on", nil, "Only retry on these exit codes or 'timeout' (comma-separated)")
+	cmd.Flags().BoolVar(&opts.Capture, "capture", false, "Store the output for replay with cb output")
+	cmd.Flags().StringArrayVar(&opts.Set, "set", nil, "Value of a {{name}} template variable of the command (NAME=VALUE, repeatable)")
+}
+
+func outputCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "output [last|<run-id>]",
+		Short: "Replay the captured output of a run",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			id := handler.LastRun
+			if len(args) > 0 {
+				id = args[0]
+			}
+
+			if err := handler.ShowOutput(configPath, id); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return getCapturedRuns(), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+	}
+
+	return cmd
+}
+
+func removeCmd() *cobra.Command {
+	var sel domain.Selector
+	var opts handler.BulkOptions
+
+	const (
+		prefixIndex   = 0
+		shortCmdIndex = 1
+		argsNum       = 2
+	)
+
+	cmd := &cobra.Command{
+		Use:     "remove <prefix> <short-cmd> | --prefix <prefix> | --match <pattern>",
+		Aliases: []string{"r", "rm"},
+		Short:   "Remove a command, or every command selected by prefix or pattern",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if !sel.IsZero() {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(argsNum)(cmd, args)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			if sel.IsZero() {
+				err = handler.RemoveCommand(configPath, args[prefixIndex], args[shortCmdIndex])
+			} else {
+				err = handler.RemoveCommands(configPath, sel, opts)
+			}
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	addSelectorFlags(cmd, &sel)
+	cmd.Flags().BoolVarP(&opts.Yes, "yes", "y", false, "Remove the selected commands without confirmation")
+
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if !sel.IsZero() {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
 		if len(args) == prefixIndex {
 			return getPrefixes(), cobra.ShellCompDirectiveNoFileComp
 		}
 		if len(args) == shortCmdIndex {
-			return getShorts(args[prefixIndex]), cobra.ShellCompDirectiveNoFileComp
+			return getShorts(args[prefixIndex]), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
 		}
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
@@ -149,7 +382,40 @@ func execCmd() *cobra.Command {
 	return cmd
 }
 
-func removeCmd() *cobra.Command {
+func moveCmd() *cobra.Command {
+	var sel domain.Selector
+	var toPrefix string
+	var opts handler.BulkOptions
+
+	cmd := &cobra.Command{
+		Use:     "move --prefix <prefix> | --match <pattern> --to <prefix>",
+		Aliases: []string{"mv"},
+		Short:   "Move every command selected by prefix or pattern to another prefix",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.MoveCommands(configPath, sel, toPrefix, opts); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	addSelectorFlags(cmd, &sel)
+	cmd.Flags().StringVar(&toPrefix, "to", "", "Prefix to move the commands to")
+	cmd.Flags().BoolVarP(&opts.Yes, "yes", "y", false, "Move the selected commands without confirmation")
+	cmd.MarkFlagRequired("to")
+
+	cmd.RegisterFlagCompletionFunc("to", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return getPrefixes(), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+func copyCmd() *cobra.Command {
+	var toPrefix, toShort string
+	var opts handler.CopyOptions
+
 	const (
 		prefixIndex   = 0
 		shortCmdIndex = 1
@@ -157,24 +423,33 @@ func removeCmd() *cobra.Command {
 	)
 
 	cmd := &cobra.Command{
-		Use:     "remove <prefix> <short-cmd>",
-		Aliases: []string{"r", "rm"},
-		Short:   "Remove a command",
+		Use:     "copy <prefix> <short>",
+		Aliases: []string{"cp"},
+		Short:   "Copy a command with its settings",
 		Args:    cobra.ExactArgs(argsNum),
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := handler.RemoveCommand(configPath, args[prefixIndex], args[shortCmdIndex]); err != nil {
+			if err := handler.CopyCommand(configPath, args[prefixIndex], args[shortCmdIndex], toPrefix, toShort, opts); err != nil {
 				fmt.Println("Error:", err)
 				os.Exit(1)
 			}
 		},
 	}
 
+	cmd.Flags().StringVarP(&toPrefix, "to-prefix", "P", "", "Prefix of the copy (default: the same prefix)")
+	cmd.Flags().StringVarP(&toShort, "to-short", "S", "", "Short name of the copy (default: the same name, numbered if taken)")
+	cmd.Flags().BoolVarP(&opts.Edit, "edit", "E", false, "Edit the copied command in $EDITOR before saving it")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Replace a command already at the target prefix and short name")
+
+	cmd.RegisterFlagCompletionFunc("to-prefix", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return getPrefixes(), cobra.ShellCompDirectiveNoFileComp
+	})
+
 	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == prefixIndex {
 			return getPrefixes(), cobra.ShellCompDirectiveNoFileComp
 		}
 		if len(args) == shortCmdIndex {
-			return getShorts(args[prefixIndex]), cobra.ShellCompDirectiveNoFileComp
+			return getShorts(args[prefixIndex]), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
 		}
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
@@ -182,6 +457,16 @@ func removeCmd() *cobra.Command {
 	return cmd
 }
 
+// addSelectorFlags adds the flags choosing the entries of a bulk operation.
+func addSelectorFlags(cmd *cobra.Command, sel *domain.Selector) {
+	cmd.Flags().StringVar(&sel.Prefix, "prefix", "", "Select every command under this prefix")
+	cmd.Flags().StringVar(&sel.Match, "match", "", "Select commands whose prefix:short or command matches a glob (e.g. 'kubectl*staging*')")
+
+	cmd.RegisterFlagCompletionFunc("prefix", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return getPrefixes(), cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
 func listCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:     "list",
@@ -196,20 +481,554 @@ func listCmd() *cobra.Command {
 	}
 }
 
-func getPrefixes() []string {
-	cfg, err := config.LoadConfig(configPath)
-	if err != nil {
-		return nil
+func pickCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pick",
+		Short: "Choose a command in the interactive viewer and print it",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.PickCommand(configPath); err != nil {
+				// Stdout is inserted into the prompt by the shell widgets.
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func initCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:       "init <zsh|bash|fish>",
+		Short:     "Print shell integration binding Ctrl-G to cb pick",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: handler.SupportedShells(),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.ShellInit(args[0]); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func syncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Synchronize the command book with git",
+	}
+
+	cmd.AddCommand(
+		syncInitCmd(),
+		syncPullCmd(),
+		syncPushCmd(),
+	)
+
+	return cmd
+}
+
+func syncInitCmd() *cobra.Command {
+	var remote string
+
+	const targetIndex = 0
+
+	cmd := &cobra.Command{
+		Use:   "init <dir-or-remote>",
+		Short: "Move the command book into a git repository",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.SyncInit(configPath, args[targetIndex], remote); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&remote, "remote", "R", "", "Remote to use when initializing a local directory")
+
+	return cmd
+}
+
+func syncPullCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pull",
+		Short: "Merge changes from the remote command book",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.SyncPull(configPath); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func syncPushCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "push",
+		Short: "Push local changes to the remote command book",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.SyncPush(configPath); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
 	}
+}
 
-	return cfg.GetRegisteredPrefixes()
+func mergeCmd() *cobra.Command {
+	const (
+		baseIndex   = 0
+		oursIndex   = 1
+		theirsIndex = 2
+		argsNum     = 3
+	)
+
+	return &cobra.Command{
+		Use:   "merge <base> <ours> <theirs>",
+		Short: "Merge two diverged command books into <ours> (usable as a git merge driver)",
+		Args:  cobra.ExactArgs(argsNum),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.MergeBooks(args[baseIndex], args[oursIndex], args[theirsIndex]); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
 }
 
-func getShorts(prefix string) []string {
-	cfg, err := config.LoadConfig(configPath)
+func diffCmd() *cobra.Command {
+	var asJSON bool
+
+	const (
+		fromIndex = 0
+		toIndex   = 1
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diff <fileA> [fileB]",
+		Short: "Show differences between two command books (or a book and the current one)",
+		Args:  cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var to string
+			if len(args) > toIndex {
+				to = args[toIndex]
+			}
+
+			if err := handler.DiffBooks(configPath, args[fromIndex], to, asJSON); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Output differences as JSON")
+
+	return cmd
+}
+
+func remoteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remote",
+		Short: "Manage read-only shared command books",
+	}
+
+	cmd.AddCommand(
+		remoteAddCmd(),
+		remoteRemoveCmd(),
+		remoteListCmd(),
+		remoteUpdateCmd(),
+	)
+
+	return cmd
+}
+
+func shimCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shim",
+		Short: "Manage executables that run commands as <prefix>-<short>",
+	}
+
+	cmd.AddCommand(
+		shimInstallCmd(),
+		shimSyncCmd(),
+	)
+
+	return cmd
+}
+
+func shimInstallCmd() *cobra.Command {
+	var dir string
+
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Create a shim for every command in a directory",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.ShimInstall(configPath, dir); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&dir, "dir", "d", "~/.local/bin", "Directory to create the shims in, ideally in PATH")
+
+	return cmd
+}
+
+func shimSyncCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "sync",
+		Short: "Update the installed shims and remove those of removed commands",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.ShimSync(configPath); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func remoteAddCmd() *cobra.Command {
+	var r config.Remote
+
+	const (
+		nameIndex = 0
+		urlIndex  = 1
+		argsNum   = 2
+	)
+
+	cmd := &cobra.Command{
+		Use:   "add <name> <url>",
+		Short: "Subscribe to a shared command book",
+		Args:  cobra.ExactArgs(argsNum),
+		Run: func(cmd *cobra.Command, args []string) {
+			r.URL = args[urlIndex]
+			if err := handler.RemoteAdd(configPath, args[nameIndex], r); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&r.Key, "key", "k", "", "Public key the book must be signed with")
+	cmd.Flags().BoolVar(&r.RequireSignature, "require-signature", false, "Refuse the book unless it is signed by a trusted key")
+	cmd.Flags().BoolVar(&r.TrustOnFirstUse, "trust-on-first-use", false, "Pin the key that signs the first fetched book")
+
+	return cmd
+}
+
+func remoteRemoveCmd() *cobra.Command {
+	const nameIndex = 0
+
+	cmd := &cobra.Command{
+		Use:     "remove <name>",
+		Aliases: []string{"rm"},
+		Short:   "Unsubscribe from a shared command book",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.RemoteRemove(configPath, args[nameIndex]); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == nameIndex {
+			return getRemotes(), cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return cmd
+}
+
+func remoteListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List subscribed command books",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.RemoteList(configPath); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func remoteUpdateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update [name...]",
+		Short: "Fetch the latest version of subscribed command books",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.RemoteUpdate(configPath, args); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return getRemotes(), cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return cmd
+}
+
+func historyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show recorded activity",
+	}
+
+	cmd.AddCommand(
+		historyExecCmd(),
+	)
+
+	return cmd
+}
+
+func historyExecCmd() *cobra.Command {
+	var opts handler.HistoryOptions
+
+	cmd := &cobra.Command{
+		Use:   "exec",
+		Short: "Show executed commands with their duration and exit code",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.HistoryExec(configPath, opts); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Failed, "failed", false, "Only show failed runs")
+	cmd.Flags().StringVar(&opts.Since, "since", "", "Only show runs within this age (e.g. 12h, 1d, 2w)")
+	cmd.Flags().StringVarP(&opts.Prefix, "prefix", "P", "", "Only show runs of this prefix")
+	cmd.Flags().IntVarP(&opts.Limit, "limit", "n", 0, "Only show the most recent runs")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Output as JSON")
+
+	cmd.RegisterFlagCompletionFunc("prefix", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return getExecPrefixes(), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+func statsCmd() *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show most used, recently used and never used commands",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.Stats(configPath, limit); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "n", 10, "Number of commands per section (0 for all)")
+
+	return cmd
+}
+
+func topCmd() *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "List commands ranked by frequency and recency of use",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.Top(configPath, limit); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "n", 10, "Number of commands to show (0 for all)")
+
+	return cmd
+}
+
+func pruneCmd() *cobra.Command {
+	var unusedFor string
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Suggest commands to remove because they are no longer used",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.Prune(configPath, unusedFor); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&unusedFor, "unused-for", "180d", "Suggest commands not run within this age (e.g. 90d, 26w)")
+
+	return cmd
+}
+
+func dedupeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "dedupe",
+		Short: "Find commands saved more than once and merge them",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.Dedupe(configPath, handler.DedupeOptions{}); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func keysCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage keys for signing and verifying shared command books",
+	}
+
+	cmd.AddCommand(
+		keysGenCmd(),
+		keysSignCmd(),
+		keysTrustCmd(),
+		keysUntrustCmd(),
+		keysListCmd(),
+	)
+
+	return cmd
+}
+
+func keysGenCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "gen",
+		Short: "Generate an ed25519 signing key",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.KeysGen(configPath, force); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Replace an existing key")
+
+	return cmd
+}
+
+func keysSignCmd() *cobra.Command {
+	const bookIndex = 0
+
+	return &cobra.Command{
+		Use:   "sign <book>",
+		Short: "Write a detached signature (<book>.sig) for a command book",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.KeysSign(configPath, args[bookIndex]); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func keysTrustCmd() *cobra.Command {
+	const (
+		nameIndex = 0
+		keyIndex  = 1
+		argsNum   = 2
+	)
+
+	return &cobra.Command{
+		Use:   "trust <name> <key-or-file>",
+		Short: "Trust a public key for verifying shared command books",
+		Args:  cobra.ExactArgs(argsNum),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.KeysTrust(configPath, args[nameIndex], args[keyIndex]); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func keysUntrustCmd() *cobra.Command {
+	const nameIndex = 0
+
+	return &cobra.Command{
+		Use:   "untrust <name>",
+		Short: "Stop trusting a public key",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.KeysUntrust(configPath, args[nameIndex]); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func keysListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List trusted public keys",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handler.KeysList(configPath); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+// readCommand returns the command given as an argument or, with --file, the
+// script read from path ("-" for stdin). Workflows have no command.
+func readCommand(args []string, index int, path string, workflow bool) (string, error) {
+	if path == "" {
+		if len(args) <= index {
+			if workflow {
+				return "", nil
+			}
+			return "", fmt.Errorf("a command or --file is required")
+		}
+		return args[index], nil
+	}
+	if len(args) > index {
+		return "", fmt.Errorf("a command and --file cannot be used together")
+	}
+
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
 	if err != nil {
-		return nil
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
 	}
 
-	return cfg.GetRegisteredShortcutsByPrefix(prefix)
+	command := strings.TrimRight(string(data), "\n")
+	if command == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return command, nil
 }
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+	"github.com/pHo9UBenaA/cmdbook/internal/history"
+	"github.com/pHo9UBenaA/cmdbook/internal/remote"
+	"github.com/pHo9UBenaA/cmdbook/pkg/ioutil"
+)
+
+// maxDescriptionLen keeps completion descriptions to a single line in most
+// terminals.
+const maxDescriptionLen = 60
+
+func completionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion <bash|zsh|fish|powershell>",
+		Short: "Print the completion script for a shell",
+		Example: `  source <(cb completion bash)            # ~/.bashrc
+  cb completion zsh > "${fpath[1]}/_cb"     # then restart zsh
+  cb completion fish | source              # ~/.config/fish/config.fish`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		Run: func(cmd *cobra.Command, args []string) {
+			root := cmd.Root()
+
+			var err error
+			switch args[0] {
+			case "bash":
+				err = root.GenBashCompletionV2(os.Stdout, true)
+			case "zsh":
+				err = root.GenZshCompletion(os.Stdout)
+			case "fish":
+				err = root.GenFishCompletion(os.Stdout, true)
+			case "powershell":
+				err = root.GenPowerShellCompletionWithDesc(os.Stdout)
+			default:
+				err = fmt.Errorf("unsupported shell: %s (use bash, zsh, fish or powershell)", args[0])
+			}
+
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+// describe appends a description to a completion, which shells supporting
+// it show next to the value.
+func describe(value, description string) string {
+	description = strings.ReplaceAll(ioutil.FirstLine(description), "\t", " ")
+	if r := []rune(description); len(r) > maxDescriptionLen {
+		description = string(r[:maxDescriptionLen-3]) + "..."
+	}
+	return value + "\t" + description
+}
+
+func getPrefixes() []string {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil
+	}
+
+	return describePrefixes(cfg)
+}
+
+func getShorts(prefix string) []string {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil
+	}
+
+	return describeShorts(cfg, prefix)
+}
+
+func getExecPrefixes() []string {
+	cfg, err := loadExecView()
+	if err != nil {
+		return nil
+	}
+
+	return describePrefixes(cfg)
+}
+
+func getExecShorts(prefix string) []string {
+	cfg, err := loadExecView()
+	if err != nil {
+		return nil
+	}
+
+	return describeShorts(cfg, prefix)
+}
+
+func describePrefixes(cfg *config.Config) []string {
+	prefixes := cfg.GetRegisteredPrefixes()
+	for i, prefix := range prefixes {
+		n := len(cfg.Commands[prefix])
+		if n == 1 {
+			prefixes[i] = describe(prefix, "1 command")
+		} else {
+			prefixes[i] = describe(prefix, fmt.Sprintf("%d commands", n))
+		}
+	}
+	return prefixes
+}
+
+// describeShorts offers the shorts of prefix with their commands, ordered
+// by frecency so that the commands used most often and most recently come
+// first.
+func describeShorts(cfg *config.Config, prefix string) []string {
+	shorts := cfg.GetRegisteredShortcutsByPrefix(prefix)
+	refs := make([]domain.Ref, len(shorts))
+	for i, short := range shorts {
+		refs[i] = domain.Ref{Prefix: prefix, Short: short}
+	}
+	history.Rank(refs, history.Scores(configPath))
+
+	for i, ref := range refs {
+		shorts[i] = describe(ref.Short, cfg.Commands[prefix][ref.Short])
+	}
+	return shorts
+}
+
+// getExecNames offers the prefixes followed by the shorts that can be run
+// without one, being saved under a single prefix.
+func getExecNames() []string {
+	cfg, err := loadExecView()
+	if err != nil {
+		return nil
+	}
+
+	prefixes := cfg.GetRegisteredPrefixes()
+	refs := handler.RankedRefs(configPath, cfg)
+	counts := make(map[string]int)
+	for _, ref := range refs {
+		counts[ref.Short]++
+	}
+
+	names := describePrefixes(cfg)
+	for _, ref := range refs {
+		if counts[ref.Short] == 1 && !slices.Contains(prefixes, ref.Short) {
+			names = append(names, describe(ref.Short, ref.Prefix+": "+cfg.Commands[ref.Prefix][ref.Short]))
+		}
+	}
+	return names
+}
+
+func loadExecView() (*config.Config, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return remote.WithRemotes(configPath, cfg)
+}
+
+func getExecRefs() []string {
+	cfg, err := loadExecView()
+	if err != nil {
+		return nil
+	}
+
+	refs := handler.RankedRefs(configPath, cfg)
+	names := make([]string, len(refs))
+	for i, ref := range refs {
+		names[i] = describe(ref.String(), cfg.Commands[ref.Prefix][ref.Short])
+	}
+	return names
+}
+
+// execArgRefs returns the entries named by the arguments of cb exec: a
+// short name saved under a single prefix, or prefix and short pairs.
+func execArgRefs(args []string) []domain.Ref {
+	if len(args) != 1 {
+		var refs []domain.Ref
+		for i := 0; i+1 < len(args); i += 2 {
+			refs = append(refs, domain.Ref{Prefix: args[i], Short: args[i+1]})
+		}
+		return refs
+	}
+
+	cfg, err := loadExecView()
+	if err != nil {
+		return nil
+	}
+	var refs []domain.Ref
+	for prefix, cmds := range cfg.Commands {
+		if _, ok := cmds[args[0]]; ok {
+			refs = append(refs, domain.Ref{Prefix: prefix, Short: args[0]})
+		}
+	}
+	if len(refs) != 1 {
+		return nil
+	}
+	return refs
+}
+
+// getTemplateValues completes --set: first the names of the template
+// variables of the entries in refs, then the values a variable was given
+// before, most recent first.
+func getTemplateValues(refs []domain.Ref, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names, values := handler.TemplateVars(configPath, refs)
+
+	name, _, hasValue := strings.Cut(toComplete, "=")
+	if !hasValue {
+		candidates := make([]string, len(names))
+		for i, name := range names {
+			candidates[i] = describe(name+"=", "template variable")
+		}
+		return candidates, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveKeepOrder
+	}
+
+	candidates := make([]string, len(values[name]))
+	for i, value := range values[name] {
+		candidates[i] = name + "=" + value
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+func getCapturedRuns() []string {
+	records, err := history.Load(configPath, history.Filter{})
+	if err != nil {
+		return nil
+	}
+
+	runs := []string{describe(handler.LastRun, "most recent captured run")}
+	for i := len(records) - 1; i >= 0; i-- {
+		if r := records[i]; r.Output {
+			runs = append(runs, describe(r.ID, fmt.Sprintf("%s %s, %s", r.Prefix, r.Short, r.Time.Local().Format("2006-01-02 15:04"))))
+		}
+	}
+	return runs
+}
+
+func getRemotes() []string {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil
+	}
+
+	remotes := make([]string, 0, len(cfg.Remotes))
+	for name, r := range cfg.Remotes {
+		remotes = append(remotes, describe(name, r.URL))
+	}
+	return remotes
+}
@@ -3,11 +3,14 @@ module github.com/pHo9UBenaA/cmdbook
 go 1.23.5
 
 require (
-	github.com/eiannone/keyboard v0.0.0-20220611211555-0d226195f203 // indirect
+	github.com/eiannone/keyboard v0.0.0-20220611211555-0d226195f203
+	github.com/pelletier/go-toml/v2 v2.2.3
+	github.com/spf13/cobra v1.8.1
+	golang.org/x/sys v0.29.0
+	golang.org/x/term v0.28.0
+)
+
+require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/pelletier/go-toml/v2 v2.2.3 // indirect
-	github.com/spf13/cobra v1.8.1 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/sys v0.29.0 // indirect
-	golang.org/x/term v0.28.0 // indirect
 )
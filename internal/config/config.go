@@ -1,5 +1,46 @@
 package config
 
+import "reflect"
+
 type Config struct {
-	Commands map[string]map[string]string `toml:"commands"`
+	Commands    map[string]map[string]string    `toml:"commands" json:"commands"`
+	Meta        map[string]map[string]EntryMeta `toml:"meta,omitempty" json:"meta,omitempty"`
+	Remotes     map[string]Remote               `toml:"remotes,omitempty" json:"remotes,omitempty"`
+	TrustedKeys map[string]string               `toml:"trusted_keys,omitempty" json:"trusted_keys,omitempty"`
+	RiskRules   []RiskRule                      `toml:"risk_rules,omitempty" json:"risk_rules,omitempty"`
+	Settings    Settings                        `toml:"settings,omitempty" json:"settings,omitempty"`
+}
+
+type Settings struct {
+	// Shell runs entries that do not set their own shell; it defaults to sh.
+	Shell string `toml:"shell,omitempty" json:"shell,omitempty"`
+	// MaxNameLength is the maximum number of characters of prefixes and
+	// short names; it defaults to 20.
+	MaxNameLength int `toml:"max_name_length,omitempty" json:"max_name_length,omitempty"`
+	// PrefixWrappers are commands, such as "proxychains", that are skipped
+	// when a prefix is taken from a command, like sudo or env.
+	PrefixWrappers []string `toml:"prefix_wrappers,omitempty" json:"prefix_wrappers,omitempty"`
+	// PrefixSubcommands are tools given with their first argument, such as
+	// "gcloud compute", that are filed under both words.
+	PrefixSubcommands []string `toml:"prefix_subcommands,omitempty" json:"prefix_subcommands,omitempty"`
+}
+
+func (s Settings) IsZero() bool {
+	return reflect.DeepEqual(s, Settings{})
+}
+
+type Remote struct {
+	URL              string `toml:"url" json:"url"`
+	Key              string `toml:"key,omitempty" json:"key,omitempty"`
+	RequireSignature bool   `toml:"require_signature,omitempty" json:"require_signature,omitempty"`
+	TrustOnFirstUse  bool   `toml:"trust_on_first_use,omitempty" json:"trust_on_first_use,omitempty"`
+}
+
+// RiskRule classifies commands matching Pattern (a regular expression) as
+// Level ("low", "medium" or "high"). User rules take precedence over the
+// built-in rules, so a "low" rule can be used to allow a command.
+type RiskRule struct {
+	Pattern string `toml:"pattern" json:"pattern"`
+	Level   string `toml:"level" json:"level"`
+	Reason  string `toml:"reason,omitempty" json:"reason,omitempty"`
 }
@@ -0,0 +1,85 @@
+package config
+
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeRenamed  ChangeKind = "renamed"
+	ChangeModified ChangeKind = "modified"
+)
+
+type Change struct {
+	Kind       ChangeKind `json:"kind"`
+	Prefix     string     `json:"prefix"`
+	Short      string     `json:"short"`
+	Command    string     `json:"command"`
+	OldPrefix  string     `json:"old_prefix,omitempty"`
+	OldShort   string     `json:"old_short,omitempty"`
+	OldCommand string     `json:"old_command,omitempty"`
+	// MetaChanged is set for modified entries whose settings changed.
+	MetaChanged bool `json:"meta_changed,omitempty"`
+}
+
+// Diff reports how to get from book a to book b. An entry removed from a
+// and added to b with the same command is reported as a rename.
+func Diff(a, b *Config) []Change {
+	var changes, removed, added []Change
+
+	for _, key := range entryKeys(a, b) {
+		before, after := a.lookup(key), b.lookup(key)
+		switch {
+		case before == nil:
+			added = append(added, Change{Kind: ChangeAdded, Prefix: key.prefix, Short: key.short, Command: after.Command})
+		case after == nil:
+			removed = append(removed, Change{Kind: ChangeRemoved, Prefix: key.prefix, Short: key.short, Command: before.Command})
+		case !before.Equal(*after):
+			changes = append(changes, Change{
+				Kind: ChangeModified, Prefix: key.prefix, Short: key.short,
+				Command: after.Command, OldCommand: before.Command,
+				MetaChanged: !before.Meta.Equal(after.Meta),
+			})
+		}
+	}
+
+	matched := make([]bool, len(added))
+	for _, r := range removed {
+		i := findRename(r, added, matched)
+		if i < 0 {
+			changes = append(changes, r)
+			continue
+		}
+
+		matched[i] = true
+		changes = append(changes, Change{
+			Kind: ChangeRenamed, Prefix: added[i].Prefix, Short: added[i].Short,
+			Command: added[i].Command, OldPrefix: r.Prefix, OldShort: r.Short,
+		})
+	}
+
+	for i, c := range added {
+		if !matched[i] {
+			changes = append(changes, c)
+		}
+	}
+
+	return changes
+}
+
+// findRename returns the index of an unmatched added entry with the same
+// command as removed, preferring one that kept its prefix.
+func findRename(removed Change, added []Change, matched []bool) int {
+	found := -1
+	for i, c := range added {
+		if matched[i] || c.Command != removed.Command {
+			continue
+		}
+		if c.Prefix == removed.Prefix {
+			return i
+		}
+		if found < 0 {
+			found = i
+		}
+	}
+	return found
+}
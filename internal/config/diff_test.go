@@ -0,0 +1,75 @@
+package config_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a    map[string]map[string]string
+		b    map[string]map[string]string
+		want []config.Change
+	}{
+		{
+			name: "identical books",
+			a:    map[string]map[string]string{"git": {"st": "git status"}},
+			b:    map[string]map[string]string{"git": {"st": "git status"}},
+			want: nil,
+		},
+		{
+			name: "added, removed and modified",
+			a:    map[string]map[string]string{"git": {"st": "git status", "co": "git checkout"}},
+			b:    map[string]map[string]string{"git": {"st": "git status -s"}, "docker": {"up": "docker compose up"}},
+			want: []config.Change{
+				{Kind: config.ChangeModified, Prefix: "git", Short: "st", Command: "git status -s", OldCommand: "git status"},
+				{Kind: config.ChangeRemoved, Prefix: "git", Short: "co", Command: "git checkout"},
+				{Kind: config.ChangeAdded, Prefix: "docker", Short: "up", Command: "docker compose up"},
+			},
+		},
+		{
+			name: "renamed within a prefix",
+			a:    map[string]map[string]string{"git": {"cmd0": "git push origin main"}},
+			b:    map[string]map[string]string{"git": {"push-main": "git push origin main"}},
+			want: []config.Change{
+				{Kind: config.ChangeRenamed, Prefix: "git", Short: "push-main", Command: "git push origin main", OldPrefix: "git", OldShort: "cmd0"},
+			},
+		},
+		{
+			name: "renamed prefers the same prefix",
+			a:    map[string]map[string]string{"git": {"st": "git status"}},
+			b:    map[string]map[string]string{"g": {"st": "git status"}, "git": {"s": "git status"}},
+			want: []config.Change{
+				{Kind: config.ChangeRenamed, Prefix: "git", Short: "s", Command: "git status", OldPrefix: "git", OldShort: "st"},
+				{Kind: config.ChangeAdded, Prefix: "g", Short: "st", Command: "git status"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := config.Diff(&config.Config{Commands: tt.a}, &config.Config{Commands: tt.b})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffMetaChanged(t *testing.T) {
+	a := &config.Config{Commands: map[string]map[string]string{"aws": {"ls": "aws s3 ls"}}}
+	b := &config.Config{Commands: map[string]map[string]string{"aws": {"ls": "aws s3 ls"}}}
+	b.SetEntry("aws", "ls", config.Entry{Command: "aws s3 ls", Meta: config.EntryMeta{Env: map[string]string{"AWS_PROFILE": "prod"}}})
+
+	got := config.Diff(a, b)
+	want := []config.Change{{
+		Kind: config.ChangeModified, Prefix: "aws", Short: "ls",
+		Command: "aws s3 ls", OldCommand: "aws s3 ls", MetaChanged: true,
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// EntryMeta holds the optional settings of an entry. It is stored in the
+// [meta.<prefix>.<short>] table next to the plain command string so that
+// books without settings keep their simple layout.
+type EntryMeta struct {
+	Dir string            `toml:"dir,omitempty" json:"dir,omitempty"`
+	Env map[string]string `toml:"env,omitempty" json:"env,omitempty"`
+	// Shell is a shell such as "bash" or an interpreter with its flags such
+	// as "python3 -c".
+	Shell string `toml:"shell,omitempty" json:"shell,omitempty"`
+	// Timeout and RetryDelay are durations such as "30s".
+	Timeout    string `toml:"timeout,omitempty" json:"timeout,omitempty"`
+	Retry      int    `toml:"retry,omitempty" json:"retry,omitempty"`
+	RetryDelay string `toml:"retry_delay,omitempty" json:"retry_delay,omitempty"`
+	// RetryOn limits retries to these exit codes and, with "timeout", to
+	// timed out attempts. Every failure is retried when it is empty.
+	RetryOn []string `toml:"retry_on,omitempty" json:"retry_on,omitempty"`
+	// Steps makes the entry a workflow running other entries in order; its
+	// command only describes the steps.
+	Steps []Step `toml:"steps,omitempty" json:"steps,omitempty"`
+}
+
+const (
+	WhenSucceeded = "succeeded"
+	WhenFailed    = "failed"
+	WhenAlways    = "always"
+)
+
+// Step references another entry as prefix:short. When decides whether it
+// runs given the outcome of the steps before it; it defaults to
+// WhenSucceeded.
+type Step struct {
+	Ref  string `toml:"ref" json:"ref"`
+	When string `toml:"when,omitempty" json:"when,omitempty"`
+}
+
+type Entry struct {
+	Command string
+	Meta    EntryMeta
+}
+
+func (m EntryMeta) IsZero() bool {
+	return reflect.DeepEqual(m.normalized(), EntryMeta{})
+}
+
+func (m EntryMeta) Equal(other EntryMeta) bool {
+	return reflect.DeepEqual(m.normalized(), other.normalized())
+}
+
+// Clone returns a copy of m that shares no maps or slices with it.
+func (m EntryMeta) Clone() EntryMeta {
+	m.Env = maps.Clone(m.Env)
+	m.RetryOn = slices.Clone(m.RetryOn)
+	m.Steps = slices.Clone(m.Steps)
+	return m
+}
+
+func (m EntryMeta) normalized() EntryMeta {
+	if len(m.Env) == 0 {
+		m.Env = nil
+	}
+	if len(m.RetryOn) == 0 {
+		m.RetryOn = nil
+	}
+	if len(m.Steps) == 0 {
+		m.Steps = nil
+	}
+	return m
+}
+
+func (e Entry) IsWorkflow() bool {
+	return len(e.Meta.Steps) > 0
+}
+
+func (e Entry) Equal(other Entry) bool {
+	return e.Command == other.Command && e.Meta.Equal(other.Meta)
+}
+
+func (c *Config) GetEntry(prefix, short string) (Entry, bool) {
+	command, ok := c.Commands[prefix][short]
+	if !ok {
+		return Entry{}, false
+	}
+	return Entry{Command: command, Meta: c.Meta[prefix][short]}, true
+}
+
+func (c *Config) SetEntry(prefix, short string, e Entry) {
+	if c.Commands == nil {
+		c.Commands = make(map[string]map[string]string)
+	}
+	if c.Commands[prefix] == nil {
+		c.Commands[prefix] = make(map[string]string)
+	}
+	c.Commands[prefix][short] = e.Command
+
+	if e.Meta.IsZero() {
+		c.deleteMeta(prefix, short)
+		return
+	}
+
+	if c.Meta == nil {
+		c.Meta = make(map[string]map[string]EntryMeta)
+	}
+	if c.Meta[prefix] == nil {
+		c.Meta[prefix] = make(map[string]EntryMeta)
+	}
+	c.Meta[prefix][short] = e.Meta
+}
+
+// DeleteEntry removes an entry and its settings, dropping the prefix once
+// it has no entries left.
+func (c *Config) DeleteEntry(prefix, short string) {
+	if cmds, ok := c.Commands[prefix]; ok {
+		delete(cmds, short)
+		if len(cmds) == 0 {
+			delete(c.Commands, prefix)
+		}
+	}
+	c.deleteMeta(prefix, short)
+}
+
+func (c *Config) deleteMeta(prefix, short string) {
+	metas, ok := c.Meta[prefix]
+	if !ok {
+		return
+	}
+
+	delete(metas, short)
+	if len(metas) == 0 {
+		delete(c.Meta, prefix)
+	}
+}
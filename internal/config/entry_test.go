@@ -0,0 +1,66 @@
+package config_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+)
+
+func TestConfig_Entries(t *testing.T) {
+	cfg := &config.Config{Commands: map[string]map[string]string{}}
+
+	cfg.SetEntry("aws", "ls", config.Entry{
+		Command: "aws s3 ls",
+		Meta:    config.EntryMeta{Dir: "~/infra", Env: map[string]string{"AWS_PROFILE": "prod"}},
+	})
+	cfg.SetEntry("aws", "whoami", config.Entry{Command: "aws sts get-caller-identity"})
+
+	entry, ok := cfg.GetEntry("aws", "ls")
+	if !ok {
+		t.Fatal("entry not found")
+	}
+	if entry.Meta.Dir != "~/infra" || entry.Meta.Env["AWS_PROFILE"] != "prod" {
+		t.Errorf("unexpected meta: %+v", entry.Meta)
+	}
+	if _, ok := cfg.Meta["aws"]["whoami"]; ok {
+		t.Error("entries without settings must not create a meta table")
+	}
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := config.SaveConfig(cfg, path); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+	loaded, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if got, _ := loaded.GetEntry("aws", "ls"); !got.Equal(entry) {
+		t.Errorf("round trip: got %+v, want %+v", got, entry)
+	}
+
+	cfg.SetEntry("aws", "ls", config.Entry{Command: "aws s3 ls", Meta: config.EntryMeta{Env: map[string]string{}}})
+	if _, ok := cfg.Meta["aws"]; ok {
+		t.Error("clearing settings should drop the meta table")
+	}
+
+	cfg.SetEntry("aws", "ls", entry)
+	cfg.DeleteEntry("aws", "ls")
+	cfg.DeleteEntry("aws", "whoami")
+	if len(cfg.Commands) != 0 || len(cfg.Meta) != 0 {
+		t.Errorf("DeleteEntry left data behind: %v %v", cfg.Commands, cfg.Meta)
+	}
+}
+
+func TestEntry_Equal(t *testing.T) {
+	a := config.Entry{Command: "make", Meta: config.EntryMeta{Env: map[string]string{}}}
+	b := config.Entry{Command: "make"}
+	if !a.Equal(b) {
+		t.Error("empty and nil env should be equal")
+	}
+
+	b.Meta.Dir = "~/src"
+	if a.Equal(b) {
+		t.Error("entries with different dirs should differ")
+	}
+}
@@ -1,24 +1,48 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 
 	"github.com/pelletier/go-toml/v2"
 )
 
-func LoadConfig(path string) (*Config, error) {
-	cfg := &Config{Commands: make(map[string]map[string]string)}
+const (
+	FormatTOML = "toml"
+	FormatJSON = "json"
+)
 
+func LoadConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if os.IsNotExist(err) {
-		return cfg, nil
+		return &Config{Commands: make(map[string]map[string]string)}, nil
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	if err := toml.Unmarshal(data, cfg); err != nil {
-		return nil, err
+	return ParseConfig(data, FormatTOML)
+}
+
+func ParseConfig(data []byte, format string) (*Config, error) {
+	cfg := &Config{Commands: make(map[string]map[string]string)}
+
+	switch format {
+	case FormatTOML:
+		if err := toml.Unmarshal(data, cfg); err != nil {
+			return nil, err
+		}
+	case FormatJSON:
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported book format: %s", format)
+	}
+
+	if cfg.Commands == nil {
+		cfg.Commands = make(map[string]map[string]string)
 	}
 	return cfg, nil
 }
@@ -0,0 +1,286 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const (
+	conflictOursMarker   = "<<<<<<< ours"
+	conflictSepMarker    = "======="
+	conflictTheirsMarker = ">>>>>>> theirs"
+)
+
+type Conflict struct {
+	Prefix string
+	Short  string
+	Ours   *Entry
+	Theirs *Entry
+}
+
+// Merge performs a three-way merge of books at the prefix/short level.
+// Entries changed on only one side are taken from that side; entries changed
+// differently on both sides are left out of the result and reported.
+// Remotes and trusted keys are merged by name and settings as a whole, with
+// our side winning when both changed; risk rules added on either side are
+// all kept.
+func Merge(base, ours, theirs *Config) (*Config, []Conflict) {
+	merged := &Config{
+		Commands:    make(map[string]map[string]string),
+		Remotes:     mergeMap(base.Remotes, ours.Remotes, theirs.Remotes),
+		TrustedKeys: mergeMap(base.TrustedKeys, ours.TrustedKeys, theirs.TrustedKeys),
+		RiskRules:   mergeRiskRules(base.RiskRules, ours.RiskRules, theirs.RiskRules),
+		Settings:    mergeValue(base.Settings, ours.Settings, theirs.Settings),
+	}
+	var conflicts []Conflict
+
+	for _, key := range entryKeys(base, ours, theirs) {
+		b, o, t := base.lookup(key), ours.lookup(key), theirs.lookup(key)
+
+		var result *Entry
+		switch {
+		case sameEntry(o, t):
+			result = o
+		case sameEntry(b, o):
+			result = t
+		case sameEntry(b, t):
+			result = o
+		default:
+			conflicts = append(conflicts, Conflict{Prefix: key.prefix, Short: key.short, Ours: o, Theirs: t})
+			continue
+		}
+
+		if result != nil {
+			merged.SetEntry(key.prefix, key.short, *result)
+		}
+	}
+
+	return merged, conflicts
+}
+
+// mergeValue takes theirs when only they changed base, and ours otherwise.
+func mergeValue[T any](base, ours, theirs T) T {
+	if reflect.DeepEqual(base, ours) {
+		return theirs
+	}
+	return ours
+}
+
+// mergeMap merges maps key by key with mergeValue; a missing key counts as
+// a value of its own, so additions and removals are merged as well.
+func mergeMap[V any](base, ours, theirs map[string]V) map[string]V {
+	var merged map[string]V
+	for _, key := range mapKeys(base, ours, theirs) {
+		value := mergeValue(lookupValue(base, key), lookupValue(ours, key), lookupValue(theirs, key))
+		if value == nil {
+			continue
+		}
+		if merged == nil {
+			merged = make(map[string]V)
+		}
+		merged[key] = *value
+	}
+	return merged
+}
+
+func lookupValue[V any](m map[string]V, key string) *V {
+	value, ok := m[key]
+	if !ok {
+		return nil
+	}
+	return &value
+}
+
+func mapKeys[V any](maps ...map[string]V) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mergeRiskRules keeps the rules of the side that changed them. When both
+// did, our rules are followed by the ones only they added, since dropping a
+// rule could let a dangerous command run unconfirmed.
+func mergeRiskRules(base, ours, theirs []RiskRule) []RiskRule {
+	if reflect.DeepEqual(base, ours) || reflect.DeepEqual(base, theirs) {
+		return mergeValue(base, ours, theirs)
+	}
+	merged := slices.Clone(ours)
+	for _, rule := range theirs {
+		if !slices.Contains(merged, rule) && !slices.Contains(base, rule) {
+			merged = append(merged, rule)
+		}
+	}
+	return merged
+}
+
+// FormatConflicts renders a merged book with one marker block per
+// conflicting entry, in the style of git conflict markers. The blocks sit
+// in the [commands.<prefix>] table, and in the [meta.<prefix>] table for
+// entries with settings, so that the book loads once the markers and one
+// side of each block are deleted.
+func FormatConflicts(merged *Config, conflicts []Conflict) ([]byte, error) {
+	data, err := toml.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make(map[string]*bytes.Buffer)
+	var headers []string
+	addBlock := func(header string, c Conflict, side func(*Entry) (string, error)) error {
+		buf, ok := blocks[header]
+		if !ok {
+			buf = &bytes.Buffer{}
+			blocks[header] = buf
+			headers = append(headers, header)
+		}
+
+		fmt.Fprintf(buf, "# conflict: %s %s\n%s\n", c.Prefix, c.Short, conflictOursMarker)
+		for i, entry := range []*Entry{c.Ours, c.Theirs} {
+			if i == 1 {
+				fmt.Fprintln(buf, conflictSepMarker)
+			}
+			if entry == nil {
+				fmt.Fprintln(buf, "# removed")
+				continue
+			}
+			line, err := side(entry)
+			if err != nil {
+				return err
+			}
+			buf.WriteString(line)
+		}
+		fmt.Fprintln(buf, conflictTheirsMarker)
+		return nil
+	}
+
+	for _, c := range conflicts {
+		commandHeader, err := tableHeader("commands", c.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		err = addBlock(commandHeader, c, func(e *Entry) (string, error) {
+			return encodeKey(c.Short, e.Command, false)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if (c.Ours == nil || c.Ours.Meta.IsZero()) && (c.Theirs == nil || c.Theirs.Meta.IsZero()) {
+			continue
+		}
+		metaHeader, err := tableHeader("meta", c.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		err = addBlock(metaHeader, c, func(e *Entry) (string, error) {
+			if e.Meta.IsZero() {
+				return "# no settings\n", nil
+			}
+			return encodeKey(c.Short, e.Meta, true)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Put each block right after the header of its table, or in a table of
+	// its own when the merged book has none.
+	var buf bytes.Buffer
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		buf.WriteString(line)
+		if block, ok := blocks[strings.TrimSpace(line)]; ok {
+			buf.Write(block.Bytes())
+			delete(blocks, strings.TrimSpace(line))
+		}
+	}
+	for _, header := range headers {
+		if block, ok := blocks[header]; ok {
+			fmt.Fprintf(&buf, "\n%s\n", header)
+			buf.Write(block.Bytes())
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// tableHeader returns the header of the table name.key as the encoder
+// writes it, quoting key where needed.
+func tableHeader(name, key string) (string, error) {
+	line, err := encodeKey(key, "", false)
+	if err != nil {
+		return "", err
+	}
+	quoted, _, _ := strings.Cut(line, " = ")
+	return "[" + name + "." + quoted + "]", nil
+}
+
+// encodeKey renders a single key/value line; inline writes tables as
+// inline tables.
+func encodeKey(key string, value any, inline bool) (string, error) {
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
+	enc.SetTablesInline(inline)
+	if err := enc.Encode(map[string]any{key: value}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+type entryKey struct {
+	prefix string
+	short  string
+}
+
+func entryKeys(books ...*Config) []entryKey {
+	seen := make(map[entryKey]bool)
+	var keys []entryKey
+	for _, book := range books {
+		for prefix, cmds := range book.Commands {
+			for short := range cmds {
+				key := entryKey{prefix, short}
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].prefix != keys[j].prefix {
+			return keys[i].prefix < keys[j].prefix
+		}
+		return keys[i].short < keys[j].short
+	})
+	return keys
+}
+
+func (c *Config) lookup(key entryKey) *Entry {
+	entry, ok := c.GetEntry(key.prefix, key.short)
+	if !ok {
+		return nil
+	}
+	return &entry
+}
+
+func sameEntry(a, b *Entry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
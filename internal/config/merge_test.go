@@ -0,0 +1,255 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name          string
+		base          map[string]map[string]string
+		ours          map[string]map[string]string
+		theirs        map[string]map[string]string
+		wantCmds      map[string]map[string]string
+		wantConflicts []string
+	}{
+		{
+			name:     "non-overlapping adds",
+			base:     map[string]map[string]string{"git": {"st": "git status"}},
+			ours:     map[string]map[string]string{"git": {"st": "git status", "co": "git checkout"}},
+			theirs:   map[string]map[string]string{"git": {"st": "git status"}, "docker": {"up": "docker compose up"}},
+			wantCmds: map[string]map[string]string{"git": {"st": "git status", "co": "git checkout"}, "docker": {"up": "docker compose up"}},
+		},
+		{
+			name:     "update on one side, remove on the other entry",
+			base:     map[string]map[string]string{"git": {"st": "git status", "co": "git checkout"}},
+			ours:     map[string]map[string]string{"git": {"st": "git status -s", "co": "git checkout"}},
+			theirs:   map[string]map[string]string{"git": {"st": "git status"}},
+			wantCmds: map[string]map[string]string{"git": {"st": "git status -s"}},
+		},
+		{
+			name:     "same change on both sides",
+			base:     map[string]map[string]string{"git": {"st": "git status"}},
+			ours:     map[string]map[string]string{"git": {"st": "git status -s"}},
+			theirs:   map[string]map[string]string{"git": {"st": "git status -s"}},
+			wantCmds: map[string]map[string]string{"git": {"st": "git status -s"}},
+		},
+		{
+			name:          "conflicting updates",
+			base:          map[string]map[string]string{"git": {"st": "git status", "co": "git checkout"}},
+			ours:          map[string]map[string]string{"git": {"st": "git status -s", "co": "git checkout"}},
+			theirs:        map[string]map[string]string{"git": {"st": "git status -b", "co": "git checkout"}},
+			wantCmds:      map[string]map[string]string{"git": {"co": "git checkout"}},
+			wantConflicts: []string{"git st"},
+		},
+		{
+			name:          "update conflicts with remove",
+			base:          map[string]map[string]string{"git": {"st": "git status"}},
+			ours:          map[string]map[string]string{},
+			theirs:        map[string]map[string]string{"git": {"st": "git status -b"}},
+			wantCmds:      map[string]map[string]string{},
+			wantConflicts: []string{"git st"},
+		},
+		{
+			name:          "different adds under the same name",
+			base:          map[string]map[string]string{},
+			ours:          map[string]map[string]string{"git": {"st": "git status -s"}},
+			theirs:        map[string]map[string]string{"git": {"st": "git status -b"}},
+			wantCmds:      map[string]map[string]string{},
+			wantConflicts: []string{"git st"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflicts := config.Merge(
+				&config.Config{Commands: tt.base},
+				&config.Config{Commands: tt.ours},
+				&config.Config{Commands: tt.theirs},
+			)
+
+			if len(merged.Commands) != len(tt.wantCmds) {
+				t.Errorf("got %d prefixes (%v), want %d (%v)", len(merged.Commands), merged.Commands, len(tt.wantCmds), tt.wantCmds)
+			}
+			for prefix, cmds := range tt.wantCmds {
+				if len(merged.Commands[prefix]) != len(cmds) {
+					t.Errorf("prefix %s: got %v, want %v", prefix, merged.Commands[prefix], cmds)
+				}
+				for short, command := range cmds {
+					if got := merged.Commands[prefix][short]; got != command {
+						t.Errorf("%s %s: got %q, want %q", prefix, short, got, command)
+					}
+				}
+			}
+
+			if len(conflicts) != len(tt.wantConflicts) {
+				t.Fatalf("got %d conflicts (%v), want %v", len(conflicts), conflicts, tt.wantConflicts)
+			}
+			for i, c := range conflicts {
+				if got := c.Prefix + " " + c.Short; got != tt.wantConflicts[i] {
+					t.Errorf("conflict %d: got %q, want %q", i, got, tt.wantConflicts[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFormatConflicts(t *testing.T) {
+	ours := config.Entry{Command: "git status -s", Meta: config.EntryMeta{Dir: "~/src"}}
+	merged := &config.Config{Commands: map[string]map[string]string{"git": {"co": "git checkout"}}}
+	conflicts := []config.Conflict{{Prefix: "git", Short: "st", Ours: &ours}}
+
+	data, err := config.FormatConflicts(merged, conflicts)
+	if err != nil {
+		t.Fatalf("FormatConflicts() error = %v", err)
+	}
+
+	got := string(data)
+	for _, want := range []string{"co = 'git checkout'", "# conflict: git st", "<<<<<<< ours", "st = 'git status -s'", "dir = '~/src'", "=======", "# removed", ">>>>>>> theirs"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestMergeKeepsMeta(t *testing.T) {
+	base := &config.Config{Commands: map[string]map[string]string{"aws": {"ls": "aws s3 ls"}}}
+	ours := &config.Config{Commands: map[string]map[string]string{}}
+	ours.SetEntry("aws", "ls", config.Entry{Command: "aws s3 ls", Meta: config.EntryMeta{Dir: "~/infra"}})
+	theirs := &config.Config{Commands: map[string]map[string]string{"aws": {"ls": "aws s3 ls", "id": "aws sts get-caller-identity"}}}
+
+	merged, conflicts := config.Merge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("unexpected conflicts: %v", conflicts)
+	}
+
+	entry, _ := merged.GetEntry("aws", "ls")
+	if entry.Meta.Dir != "~/infra" {
+		t.Errorf("settings changed on our side were lost: %+v", entry)
+	}
+	if _, ok := merged.GetEntry("aws", "id"); !ok {
+		t.Error("entry added on their side is missing")
+	}
+}
+
+func TestMergeSections(t *testing.T) {
+	base := &config.Config{
+		Remotes:     map[string]config.Remote{"team": {URL: "https://example.com/team.toml"}},
+		TrustedKeys: map[string]string{"alice": "AAAA"},
+		RiskRules:   []config.RiskRule{{Pattern: "helmfile .*sync", Level: "high"}},
+		Settings:    config.Settings{Shell: "bash"},
+	}
+	ours := &config.Config{
+		Remotes:     map[string]config.Remote{"team": {URL: "https://example.com/team.toml", RequireSignature: true}},
+		TrustedKeys: map[string]string{"alice": "AAAA", "bob": "BBBB"},
+		RiskRules:   []config.RiskRule{{Pattern: "helmfile .*sync", Level: "high"}, {Pattern: "terraform destroy", Level: "high"}},
+		Settings:    config.Settings{Shell: "bash"},
+	}
+	theirs := &config.Config{
+		Commands:    map[string]map[string]string{"git": {"st": "git status"}},
+		Remotes:     map[string]config.Remote{"team": {URL: "https://example.com/team.toml"}, "ops": {URL: "https://example.com/ops.toml"}},
+		TrustedKeys: map[string]string{},
+		RiskRules:   []config.RiskRule{{Pattern: "helmfile .*sync", Level: "high"}, {Pattern: "kubectl drain", Level: "high"}},
+		Settings:    config.Settings{Shell: "zsh", MaxNameLength: 30},
+	}
+
+	merged, conflicts := config.Merge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("unexpected conflicts: %v", conflicts)
+	}
+
+	wantRemotes := map[string]config.Remote{
+		"team": {URL: "https://example.com/team.toml", RequireSignature: true},
+		"ops":  {URL: "https://example.com/ops.toml"},
+	}
+	if !reflect.DeepEqual(merged.Remotes, wantRemotes) {
+		t.Errorf("remotes = %v, want %v", merged.Remotes, wantRemotes)
+	}
+	// alice was removed on their side; bob was added on ours.
+	if !reflect.DeepEqual(merged.TrustedKeys, map[string]string{"bob": "BBBB"}) {
+		t.Errorf("trusted keys = %v", merged.TrustedKeys)
+	}
+	wantRules := []config.RiskRule{
+		{Pattern: "helmfile .*sync", Level: "high"},
+		{Pattern: "terraform destroy", Level: "high"},
+		{Pattern: "kubectl drain", Level: "high"},
+	}
+	if !reflect.DeepEqual(merged.RiskRules, wantRules) {
+		t.Errorf("risk rules = %v, want %v", merged.RiskRules, wantRules)
+	}
+	if !reflect.DeepEqual(merged.Settings, config.Settings{Shell: "zsh", MaxNameLength: 30}) {
+		t.Errorf("settings = %+v, want theirs", merged.Settings)
+	}
+}
+
+func TestFormatConflictsResolves(t *testing.T) {
+	ours := config.Entry{Command: "git status -s", Meta: config.EntryMeta{Dir: "~/src", Env: map[string]string{"GIT_PAGER": "cat"}}}
+	theirs := config.Entry{Command: "git status --short"}
+	merged := &config.Config{Commands: map[string]map[string]string{}}
+	merged.SetEntry("git", "co", config.Entry{Command: "git checkout", Meta: config.EntryMeta{Shell: "bash"}})
+	conflicts := []config.Conflict{
+		{Prefix: "git", Short: "st", Ours: &ours, Theirs: &theirs},
+		{Prefix: "make", Short: "b", Ours: &config.Entry{Command: "make build"}},
+	}
+
+	data, err := config.FormatConflicts(merged, conflicts)
+	if err != nil {
+		t.Fatalf("FormatConflicts() error = %v", err)
+	}
+
+	for _, side := range []string{"ours", "theirs"} {
+		t.Run(side, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "book.toml")
+			if err := os.WriteFile(path, resolveConflicts(string(data), side == "ours"), 0644); err != nil {
+				t.Fatalf("failed to write book: %v", err)
+			}
+
+			cfg, err := config.LoadConfig(path)
+			if err != nil {
+				t.Fatalf("resolved book does not load: %v\n%s", err, data)
+			}
+
+			want := ours
+			if side == "theirs" {
+				want = theirs
+			}
+			if got, _ := cfg.GetEntry("git", "st"); !got.Equal(want) {
+				t.Errorf("git st = %+v, want %+v", got, want)
+			}
+			if _, ok := cfg.GetEntry("make", "b"); ok != (side == "ours") {
+				t.Errorf("make b kept = %v", ok)
+			}
+			if got, _ := cfg.GetEntry("git", "co"); got.Meta.Shell != "bash" {
+				t.Errorf("git co = %+v", got)
+			}
+		})
+	}
+}
+
+// resolveConflicts keeps one side of every marker block, as a user would
+// when resolving the conflicts by hand.
+func resolveConflicts(book string, keepOurs bool) []byte {
+	var out []string
+	keep := true
+	for _, line := range strings.Split(book, "\n") {
+		switch {
+		case strings.HasPrefix(line, "<<<<<<< "):
+			keep = keepOurs
+		case line == "=======":
+			keep = !keepOurs
+		case strings.HasPrefix(line, ">>>>>>> "):
+			keep = true
+		default:
+			if keep {
+				out = append(out, line)
+			}
+		}
+	}
+	return []byte(strings.Join(out, "\n"))
+}
@@ -0,0 +1,10 @@
+package config
+
+import "path/filepath"
+
+const dataDirName = ".cmdbook"
+
+// DataDir returns the directory holding cmdbook's local state for the book at configPath.
+func DataDir(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), dataDirName)
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "regexp"
+
+var argsRef = regexp.MustCompile(`\$(@|\*|\{[@*]\})`)
+
+// RefersToArgs reports whether command places its arguments itself with
+// "$@" or "$*", so that they are passed to it rather than appended.
+func RefersToArgs(command string) bool {
+	return argsRef.MatchString(command)
+}
+
+// AcceptsAppendedArgs reports whether arguments appended to command end up
+// as arguments of its last program. They do not when the command ends in
+// an operator, where they would run as a command of their own, or in a
+// comment, which swallows them.
+func AcceptsAppendedArgs(command string) bool {
+	_, operatorLast, comment := scanWords(command)
+	return !operatorLast && !comment
+}
@@ -0,0 +1,53 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+)
+
+func TestAcceptsAppendedArgs(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected bool
+	}{
+		{command: "git push", expected: true},
+		{command: "echo 'a; b' \"#1\"", expected: true},
+		{command: "echo $(date)", expected: true},
+		{command: "cd src && make", expected: true},
+		{command: "echo hi;", expected: false},
+		{command: "echo hi &", expected: false},
+		{command: "ls |", expected: false},
+		{command: "echo a # note", expected: false},
+		{command: "echo a#b", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if got := domain.AcceptsAppendedArgs(tt.command); got != tt.expected {
+				t.Errorf("AcceptsAppendedArgs(%q) = %v, want %v", tt.command, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRefersToArgs(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected bool
+	}{
+		{command: `grep -r "$@" src`, expected: true},
+		{command: `echo ${@}`, expected: true},
+		{command: `echo "$*"`, expected: true},
+		{command: `awk '{print $1}'`, expected: false},
+		{command: `echo $HOME`, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if got := domain.RefersToArgs(tt.command); got != tt.expected {
+				t.Errorf("RefersToArgs(%q) = %v, want %v", tt.command, got, tt.expected)
+			}
+		})
+	}
+}
@@ -1,11 +1,13 @@
 package domain
 
+import "sort"
+
 func GroupCommands(commands map[string]map[string]string) map[string][]CommandEntry {
 	grouped := make(map[string][]CommandEntry)
 	for prefix, cmds := range commands {
 		var entries []CommandEntry
 		for short, cmd := range cmds {
-			entries = append(entries, CommandEntry{prefix, short, cmd})
+			entries = append(entries, CommandEntry{Prefix: prefix, Short: short, Command: cmd})
 		}
 		grouped[prefix] = entries
 	}
@@ -20,3 +22,42 @@ func PrepareInteractiveEntries(grouped map[string][]CommandEntry) []CommandEntry
 	}
 	return entries
 }
+
+// RankEntries lays out grouped entries like PrepareInteractiveEntries, but
+// ordered by score: prefixes by their best entry, entries within a prefix
+// by their own score, ties by name.
+func RankEntries(grouped map[string][]CommandEntry, scores map[Ref]float64) []CommandEntry {
+	best := make(map[string]float64, len(grouped))
+	prefixes := make([]string, 0, len(grouped))
+	for prefix, cmds := range grouped {
+		prefixes = append(prefixes, prefix)
+		for _, cmd := range cmds {
+			if score := scores[Ref{Prefix: prefix, Short: cmd.Short}]; score > best[prefix] {
+				best[prefix] = score
+			}
+		}
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		if best[prefixes[i]] != best[prefixes[j]] {
+			return best[prefixes[i]] > best[prefixes[j]]
+		}
+		return prefixes[i] < prefixes[j]
+	})
+
+	var entries []CommandEntry
+	for _, prefix := range prefixes {
+		cmds := append([]CommandEntry(nil), grouped[prefix]...)
+		sort.Slice(cmds, func(i, j int) bool {
+			si := scores[Ref{Prefix: prefix, Short: cmds[i].Short}]
+			sj := scores[Ref{Prefix: prefix, Short: cmds[j].Short}]
+			if si != sj {
+				return si > sj
+			}
+			return cmds[i].Short < cmds[j].Short
+		})
+
+		entries = append(entries, CommandEntry{Prefix: prefix})
+		entries = append(entries, cmds...)
+	}
+	return entries
+}
@@ -1,7 +1,8 @@
 package domain
 
 type CommandEntry struct {
-	Prefix  string
-	Short   string
-	Command string
+	Prefix   string
+	Short    string
+	Command  string
+	HighRisk bool
 }
@@ -2,6 +2,7 @@ package domain_test
 
 import (
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/pHo9UBenaA/cmdbook/internal/domain"
@@ -244,3 +245,29 @@ func TestPrepareInteractiveEntries(t *testing.T) {
 		})
 	}
 }
+
+func TestRankEntries(t *testing.T) {
+	grouped := domain.GroupCommands(map[string]map[string]string{
+		"docker": {"up": "docker compose up", "down": "docker compose down"},
+		"git":    {"st": "git status", "co": "git checkout", "push": "git push"},
+		"k8s":    {"pods": "kubectl get pods"},
+	})
+	scores := map[domain.Ref]float64{
+		{Prefix: "git", Short: "push"}:    3,
+		{Prefix: "docker", Short: "down"}: 1,
+	}
+
+	var got []string
+	for _, e := range domain.RankEntries(grouped, scores) {
+		got = append(got, e.Prefix+" "+e.Short)
+	}
+
+	want := []string{
+		"git ", "git push", "git co", "git st",
+		"docker ", "docker down", "docker up",
+		"k8s ", "k8s pods",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("RankEntries() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// NormalizeCommand collapses runs of whitespace in command, so that
+// commands differing only in spacing compare equal.
+func NormalizeCommand(command string) string {
+	return strings.Join(strings.Fields(command), " ")
+}
+
+// DuplicateGroup is a set of entries running the same or nearly the same
+// command.
+type DuplicateGroup struct {
+	Refs []Ref
+	// Exact is set when the entries are the same apart from whitespace in
+	// their commands, so that any one of them can replace the others.
+	Exact bool
+}
+
+// FindDuplicates groups the refs whose commands are equal after
+// NormalizeCommand, or within a few edits of each other. Refs with equal
+// commands only form an exact group when sameEntry reports that the rest
+// of their entries, such as their settings, is the same too. A group holds
+// the refs close to its first command rather than every chain of close
+// commands. Groups and the refs in them are sorted by prefix and short.
+func FindDuplicates(commands map[Ref]string, sameEntry func(a, b Ref) bool) []DuplicateGroup {
+	refs := make([]Ref, 0, len(commands))
+	for ref := range commands {
+		refs = append(refs, ref)
+	}
+	sortRefs(refs)
+
+	// A unit holds refs that are exact duplicates of each other.
+	type unit struct {
+		text string
+		refs []Ref
+	}
+	var units []*unit
+	for _, ref := range refs {
+		text := NormalizeCommand(commands[ref])
+		var found *unit
+		for _, u := range units {
+			if u.text == text && sameEntry(u.refs[0], ref) {
+				found = u
+				break
+			}
+		}
+		if found == nil {
+			units = append(units, &unit{text: text, refs: []Ref{ref}})
+		} else {
+			found.refs = append(found.refs, ref)
+		}
+	}
+
+	var groups []DuplicateGroup
+	grouped := make([]bool, len(units))
+	for i, u := range units {
+		if grouped[i] {
+			continue
+		}
+		grouped[i] = true
+
+		group := DuplicateGroup{Refs: slices.Clone(u.refs), Exact: true}
+		for j := i + 1; j < len(units); j++ {
+			if !grouped[j] && (units[j].text == u.text || nearDuplicate(u.text, units[j].text)) {
+				grouped[j] = true
+				group.Refs = append(group.Refs, units[j].refs...)
+				group.Exact = false
+			}
+		}
+		if len(group.Refs) < 2 {
+			continue
+		}
+		sortRefs(group.Refs)
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// nearDuplicate reports whether a and b differ by about one edit per ten
+// characters, and at most two edits for short commands.
+func nearDuplicate(a, b string) bool {
+	na, nb := utf8.RuneCountInString(a), utf8.RuneCountInString(b)
+	maxDistance := max(2, max(na, nb)/10)
+	if na-nb > maxDistance || nb-na > maxDistance {
+		return false
+	}
+	return Distance(a, b) <= maxDistance
+}
+
+func sortRefs(refs []Ref) {
+	sort.Slice(refs, func(i, j int) bool { return lessRef(refs[i], refs[j]) })
+}
+
+func lessRef(a, b Ref) bool {
+	if a.Prefix != b.Prefix {
+		return a.Prefix < b.Prefix
+	}
+	return a.Short < b.Short
+}
@@ -0,0 +1,111 @@
+package domain_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	ref := func(prefix, short string) domain.Ref { return domain.Ref{Prefix: prefix, Short: short} }
+
+	tests := []struct {
+		name     string
+		commands map[domain.Ref]string
+		settings map[domain.Ref]string
+		expected []domain.DuplicateGroup
+	}{
+		{
+			name: "no duplicates",
+			commands: map[domain.Ref]string{
+				ref("git", "push"): "git push origin main",
+				ref("git", "pull"): "git pull --rebase",
+			},
+		},
+		{
+			name: "same command apart from whitespace",
+			commands: map[domain.Ref]string{
+				ref("git", "pm"):        "git push origin main",
+				ref("git", "push-main"): "git  push origin\tmain ",
+				ref("git", "pull"):      "git pull --rebase",
+			},
+			expected: []domain.DuplicateGroup{
+				{Refs: []domain.Ref{ref("git", "pm"), ref("git", "push-main")}, Exact: true},
+			},
+		},
+		{
+			name: "similar commands across prefixes",
+			commands: map[domain.Ref]string{
+				ref("k8s", "pods"):   "kubectl get pods -n prod",
+				ref("kubectl", "gp"): "kubectl get pod -n prod",
+				ref("kubectl", "gs"): "kubectl get svc -n prod",
+			},
+			expected: []domain.DuplicateGroup{
+				{Refs: []domain.Ref{ref("k8s", "pods"), ref("kubectl", "gp")}},
+			},
+		},
+		{
+			name: "short commands within two edits",
+			commands: map[domain.Ref]string{
+				ref("ls", "all"):  "ls -la",
+				ref("ls", "long"): "ls -al",
+				ref("ls", "tree"): "tree -L 2",
+			},
+			expected: []domain.DuplicateGroup{
+				{Refs: []domain.Ref{ref("ls", "all"), ref("ls", "long")}},
+			},
+		},
+		{
+			name: "same command with different settings",
+			commands: map[domain.Ref]string{
+				ref("tf", "apply-dev"):  "terraform apply",
+				ref("tf", "apply-prod"): "terraform apply",
+				ref("tf", "apply"):      "terraform  apply",
+			},
+			settings: map[domain.Ref]string{
+				ref("tf", "apply-dev"):  "AWS_PROFILE=dev",
+				ref("tf", "apply-prod"): "AWS_PROFILE=prod",
+				ref("tf", "apply"):      "AWS_PROFILE=prod",
+			},
+			expected: []domain.DuplicateGroup{
+				{Refs: []domain.Ref{ref("tf", "apply"), ref("tf", "apply-dev"), ref("tf", "apply-prod")}},
+			},
+		},
+		{
+			name: "exact duplicates with the same settings",
+			commands: map[domain.Ref]string{
+				ref("tf", "apply"):      "terraform  apply",
+				ref("tf", "apply-prod"): "terraform apply",
+			},
+			settings: map[domain.Ref]string{
+				ref("tf", "apply"):      "AWS_PROFILE=prod",
+				ref("tf", "apply-prod"): "AWS_PROFILE=prod",
+			},
+			expected: []domain.DuplicateGroup{
+				{Refs: []domain.Ref{ref("tf", "apply"), ref("tf", "apply-prod")}, Exact: true},
+			},
+		},
+		{
+			name: "chains of close commands are not joined",
+			commands: map[domain.Ref]string{
+				ref("make", "a1"): "make a",
+				ref("make", "a2"): "make abc",
+				ref("make", "a3"): "make abcde",
+			},
+			expected: []domain.DuplicateGroup{
+				{Refs: []domain.Ref{ref("make", "a1"), ref("make", "a2")}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sameEntry := func(a, b domain.Ref) bool { return tt.settings[a] == tt.settings[b] }
+			groups := domain.FindDuplicates(tt.commands, sameEntry)
+			if !reflect.DeepEqual(groups, tt.expected) {
+				t.Errorf("FindDuplicates() = %+v, want %+v", groups, tt.expected)
+			}
+		})
+	}
+}
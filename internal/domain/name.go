@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/constant"
+)
+
+// DefaultMaxNameLen is the maximum length of a prefix or short name, in
+// characters, unless configured otherwise.
+const DefaultMaxNameLen = constant.MaxShortLen
+
+const (
+	KindPrefix = "prefix"
+	KindShort  = "short name"
+	KindRemote = "remote name"
+)
+
+// reservedNames cannot be used as file names on Windows, where books are
+// synced and shims installed as well.
+var reservedNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true,
+	"com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true,
+	"lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// NamePolicy decides which prefixes and short names are accepted. Names
+// are used as TOML keys, shell words, completions and parts of file names,
+// so they are limited to letters, digits, '-' and '_'.
+type NamePolicy struct {
+	// MaxLen is the maximum number of characters; zero means
+	// DefaultMaxNameLen.
+	MaxLen int
+}
+
+func (p NamePolicy) ValidatePrefix(name string) error {
+	return p.validate(KindPrefix, name)
+}
+
+func (p NamePolicy) ValidateShort(name string) error {
+	return p.validate(KindShort, name)
+}
+
+// ValidateRemote checks the name of a remote, which prefixes the entries
+// of its book.
+func (p NamePolicy) ValidateRemote(name string) error {
+	return p.validate(KindRemote, name)
+}
+
+func (p NamePolicy) validate(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s must not be empty", kind)
+	}
+
+	for _, r := range name {
+		if isNameChar(r) {
+			continue
+		}
+		return fmt.Errorf("invalid %s '%s': %s is not allowed; use letters, digits, '-' or '_'", kind, name, describeRune(r))
+	}
+	if r, _ := utf8.DecodeRuneInString(name); !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+		return fmt.Errorf("invalid %s '%s': must start with a letter or digit", kind, name)
+	}
+
+	maxLen := p.MaxLen
+	if maxLen <= 0 {
+		maxLen = DefaultMaxNameLen
+	}
+	if n := utf8.RuneCountInString(name); n > maxLen {
+		return fmt.Errorf("invalid %s '%s': %d characters is over the maximum of %d", kind, name, n, maxLen)
+	}
+
+	if reservedNames[strings.ToLower(name)] {
+		return fmt.Errorf("invalid %s '%s': reserved as a file name on Windows", kind, name)
+	}
+	return nil
+}
+
+// ValidName reports whether name uses only the characters of prefixes and
+// short names and starts with a letter or digit, whatever its length. It
+// suits names joined from several of them, such as the files of shims.
+func ValidName(name string) bool {
+	if name == "" || reservedNames[strings.ToLower(name)] {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(name); !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+		return false
+	}
+	return !strings.ContainsFunc(name, func(r rune) bool { return !isNameChar(r) })
+}
+
+func isNameChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_'
+}
+
+func describeRune(r rune) string {
+	switch {
+	case r == ' ':
+		return "a space"
+	case unicode.IsSpace(r):
+		return "whitespace"
+	case unicode.IsControl(r):
+		return "a control character"
+	default:
+		return fmt.Sprintf("'%c'", r)
+	}
+}
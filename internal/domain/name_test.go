@@ -0,0 +1,73 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+)
+
+func TestNamePolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        domain.NamePolicy
+		input         string
+		expectedError string
+	}{
+		{name: "letters digits dashes and underscores", input: "push-main_2"},
+		{name: "non-ASCII letters", input: "デプロイ"},
+		{name: "empty", input: "", expectedError: "short name must not be empty"},
+		{name: "space", input: "push main", expectedError: "invalid short name 'push main': a space is not allowed; use letters, digits, '-' or '_'"},
+		{name: "tab", input: "push\tmain", expectedError: "invalid short name 'push\tmain': whitespace is not allowed; use letters, digits, '-' or '_'"},
+		{name: "dot", input: "v1.2", expectedError: "invalid short name 'v1.2': '.' is not allowed; use letters, digits, '-' or '_'"},
+		{name: "equals", input: "a=b", expectedError: "invalid short name 'a=b': '=' is not allowed; use letters, digits, '-' or '_'"},
+		{name: "colon", input: "team:up", expectedError: "invalid short name 'team:up': ':' is not allowed; use letters, digits, '-' or '_'"},
+		{name: "leading dash", input: "-f", expectedError: "invalid short name '-f': must start with a letter or digit"},
+		{name: "default maximum", input: "abcdefghijklmnopqrstu", expectedError: "invalid short name 'abcdefghijklmnopqrstu': 21 characters is over the maximum of 20"},
+		{name: "length in characters", input: "あいうえおかきくけこさしすせそたちつてと"},
+		{name: "configured maximum", policy: domain.NamePolicy{MaxLen: 3}, input: "push", expectedError: "invalid short name 'push': 4 characters is over the maximum of 3"},
+		{name: "reserved", input: "Con", expectedError: "invalid short name 'Con': reserved as a file name on Windows"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.ValidateShort(tt.input)
+			if tt.expectedError == "" {
+				if err != nil {
+					t.Errorf("ValidateShort(%q) error = %v", tt.input, err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expectedError {
+				t.Errorf("ValidateShort(%q) error = %v, want %q", tt.input, err, tt.expectedError)
+			}
+		})
+	}
+
+	if err := (domain.NamePolicy{}).ValidatePrefix("my prefix"); err == nil || err.Error() != "invalid prefix 'my prefix': a space is not allowed; use letters, digits, '-' or '_'" {
+		t.Errorf("ValidatePrefix() error = %v", err)
+	}
+	if err := (domain.NamePolicy{}).ValidateRemote("team:a"); err == nil || err.Error() != "invalid remote name 'team:a': ':' is not allowed; use letters, digits, '-' or '_'" {
+		t.Errorf("ValidateRemote() error = %v", err)
+	}
+}
+
+func TestValidName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{input: "git-push-main", expected: true},
+		{input: "a-very-long-prefix-with-a-long-short-name", expected: true},
+		{input: "v1.2-up", expected: false},
+		{input: "c++-build", expected: false},
+		{input: "-f", expected: false},
+		{input: "nul", expected: false},
+		{input: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		if got := domain.ValidName(tt.input); got != tt.expected {
+			t.Errorf("ValidName(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+}
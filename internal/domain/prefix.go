@@ -0,0 +1,268 @@
+package domain
+
+import (
+	"path"
+	"strings"
+	"unicode"
+)
+
+// wrapper describes a command that runs the command following it, such as
+// sudo. flags lists its single-letter options taking a value; positional
+// is the number of arguments before the wrapped command.
+type wrapper struct {
+	flags      string
+	positional int
+}
+
+var builtinWrappers = map[string]wrapper{
+	"sudo":    {flags: "CDghprTtUu"},
+	"doas":    {flags: "Cu"},
+	"env":     {flags: "CPSu"},
+	"time":    {flags: "fo"},
+	"nohup":   {},
+	"nice":    {flags: "n"},
+	"ionice":  {flags: "cnp"},
+	"timeout": {flags: "ks", positional: 1},
+	"exec":    {},
+	"command": {},
+}
+
+// builtinSubcommands are tools whose first argument is part of the prefix,
+// so that "docker compose up" is filed with "docker-compose up".
+var builtinSubcommands = []string{"docker compose", "podman compose"}
+
+// scriptExts are dropped from the names of scripts used as prefixes.
+var scriptExts = map[string]bool{".sh": true, ".bash": true, ".zsh": true, ".py": true, ".rb": true, ".pl": true, ".js": true, ".ts": true, ".ps1": true}
+
+// dirChangers are skipped together with the commands chained after them,
+// as in "cd app && make".
+var dirChangers = map[string]bool{"cd": true, "pushd": true}
+
+// PrefixRules extend the built-in rules of DetectPrefix.
+type PrefixRules struct {
+	// Wrappers are commands, such as "doas", that run the command after
+	// them and are skipped.
+	Wrappers []string
+	// Subcommands are tools given with their first argument, such as
+	// "gcloud compute", whose argument becomes part of the prefix.
+	Subcommands []string
+}
+
+// CommandLine is a command split into the program it runs and the
+// arguments of the program.
+type CommandLine struct {
+	// Prefix names the program, such as "git" or "docker-compose".
+	Prefix string
+	// Program holds the words naming the program in the command.
+	Program []string
+	// Args are the words after the program, up to the first shell
+	// operator.
+	Args []string
+}
+
+// DetectPrefix finds the program run by the first line of command, past
+// wrappers such as sudo, environment assignments and leading "cd dir &&".
+// Paths are reduced to their base name. The prefix is empty when no
+// program is found.
+func DetectPrefix(command string, rules PrefixRules) CommandLine {
+	words := splitWords(firstCommandLine(command))
+
+	i := 0
+	for i < len(words) {
+		word := words[i]
+		switch {
+		case isAssignment(word), word == "(":
+			i++
+			continue
+		case dirChangers[word]:
+			next := nextCommand(words, i)
+			if next < 0 {
+				return programAt(words, i, rules)
+			}
+			i = next
+			continue
+		}
+
+		w, ok := findWrapper(path.Base(word), rules)
+		if !ok || i+1 == len(words) || shellOperators[words[i+1]] {
+			return programAt(words, i, rules)
+		}
+		i = skipWrapper(words, i+1, w)
+	}
+	return CommandLine{}
+}
+
+func findWrapper(name string, rules PrefixRules) (wrapper, bool) {
+	if w, ok := builtinWrappers[name]; ok {
+		return w, true
+	}
+	for _, extra := range rules.Wrappers {
+		if extra == name {
+			return wrapper{}, true
+		}
+	}
+	return wrapper{}, false
+}
+
+// skipWrapper returns the index of the first word after the options and
+// positional arguments of a wrapper, starting at i.
+func skipWrapper(words []string, i int, w wrapper) int {
+	for i < len(words) && strings.HasPrefix(words[i], "-") {
+		flag := words[i]
+		i++
+		if flag == "--" {
+			break
+		}
+		if len(flag) == 2 && strings.ContainsRune(w.flags, rune(flag[1])) {
+			i++
+		}
+	}
+	return min(i+w.positional, len(words))
+}
+
+// nextCommand returns the index of the command chained after the one at i
+// with && or ;, or -1.
+func nextCommand(words []string, i int) int {
+	for ; i < len(words); i++ {
+		if words[i] == "&&" || words[i] == ";" {
+			if i+1 < len(words) {
+				return i + 1
+			}
+			return -1
+		}
+		if shellOperators[words[i]] {
+			return -1
+		}
+	}
+	return -1
+}
+
+func programAt(words []string, i int, rules PrefixRules) CommandLine {
+	if shellOperators[words[i]] {
+		return CommandLine{}
+	}
+
+	line := CommandLine{Program: words[i : i+1]}
+	if i+1 < len(words) {
+		pair := programName(words[i:i+1]) + " " + words[i+1]
+		for _, sub := range append(builtinSubcommands, rules.Subcommands...) {
+			if pair == sub {
+				line.Program = words[i : i+2]
+				break
+			}
+		}
+	}
+	line.Prefix = sanitizeName(programName(line.Program))
+
+	for _, word := range words[i+len(line.Program):] {
+		if shellOperators[word] {
+			break
+		}
+		line.Args = append(line.Args, word)
+	}
+	return line
+}
+
+// programName joins the words naming a program with dashes, using the base
+// name of a path without the extension of a script.
+func programName(program []string) string {
+	name := path.Base(program[0])
+	if scriptExts[path.Ext(name)] {
+		name = strings.TrimSuffix(name, path.Ext(name))
+	}
+	return strings.Join(append([]string{name}, program[1:]...), "-")
+}
+
+func isAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	if !ok || name == "" {
+		return false
+	}
+	for i, r := range name {
+		valid := r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))
+		if !valid {
+			return false
+		}
+	}
+	return true
+}
+
+// sanitizeName replaces the characters not allowed in names with dashes.
+func sanitizeName(name string) string {
+	mapped := strings.Map(func(r rune) rune {
+		if isNameChar(r) {
+			return r
+		}
+		return '-'
+	}, name)
+	return strings.Trim(mapped, "-_")
+}
+
+// splitWords splits a command line into words the way a shell would,
+// honouring quotes and backslashes. Operators such as && and | are
+// returned as words of their own, and a # starting a word ends the line.
+func splitWords(line string) []string {
+	words, _, _ := scanWords(line)
+	return words
+}
+
+// scanWords is splitWords that also reports whether the line ends in an
+// operator other than ")" and whether it ends in a comment.
+func scanWords(line string) (words []string, operatorLast, comment bool) {
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	flush := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+			operatorLast = false
+		}
+	}
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' && i+1 < len(runes) {
+				i++
+				word.WriteRune(runes[i])
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\' && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case unicode.IsSpace(r):
+			flush()
+		case r == '#' && !inWord:
+			flush()
+			return words, operatorLast, true
+		case strings.ContainsRune(";&|<>()", r):
+			flush()
+			op := string(r)
+			if i+1 < len(runes) && (runes[i+1] == r && r != ';' && r != '(' && r != ')') {
+				op += string(r)
+				i++
+			}
+			words = append(words, op)
+			// A closing parenthesis ends a subshell or command
+			// substitution; words after it are not a new command.
+			operatorLast = r != ')'
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	flush()
+	return words, operatorLast, false
+}
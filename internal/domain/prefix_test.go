@@ -0,0 +1,53 @@
+package domain_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+)
+
+func TestDetectPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		rules    domain.PrefixRules
+		expected string
+		args     []string
+	}{
+		{name: "first word", command: "git push origin main", expected: "git", args: []string{"push", "origin", "main"}},
+		{name: "sudo", command: "sudo apt update", expected: "apt", args: []string{"update"}},
+		{name: "sudo with options", command: "sudo -E -u root apt install curl", expected: "apt", args: []string{"install", "curl"}},
+		{name: "nested wrappers", command: "time nice -n 10 nohup make all", expected: "make", args: []string{"all"}},
+		{name: "timeout with a duration", command: "timeout 10s curl -sS example.com", expected: "curl", args: []string{"-sS", "example.com"}},
+		{name: "assignments", command: "FOO=1 BAR='a b' make test", expected: "make", args: []string{"test"}},
+		{name: "env with options and assignments", command: "env -u HOME LANG=C sort file", expected: "sort", args: []string{"file"}},
+		{name: "cd chain", command: "cd ~/app && npm run build", expected: "npm", args: []string{"run", "build"}},
+		{name: "subshell with cd", command: "(cd app; make) && echo done", expected: "make"},
+		{name: "cd alone", command: "cd /tmp", expected: "cd", args: []string{"/tmp"}},
+		{name: "script path", command: "./scripts/deploy.sh prod", expected: "deploy", args: []string{"prod"}},
+		{name: "absolute path", command: "/usr/local/bin/terraform plan", expected: "terraform", args: []string{"plan"}},
+		{name: "subcommand tool", command: "docker compose up -d", expected: "docker-compose", args: []string{"up", "-d"}},
+		{name: "wrapper alone", command: "nohup", expected: "nohup"},
+		{name: "wrapper before an operator", command: "time && make", expected: "time"},
+		{name: "quoted words", command: `sudo "my tool" --flag 'a b'`, expected: "my-tool", args: []string{"--flag", "a b"}},
+		{name: "stops at operators", command: "git log | less", expected: "git", args: []string{"log"}},
+		{name: "first line of a script", command: "#!/bin/sh\n\n# build\nmake -j4\nmake install\n", expected: "make", args: []string{"-j4"}},
+		{name: "user wrapper", command: "proxychains curl example.com", rules: domain.PrefixRules{Wrappers: []string{"proxychains"}}, expected: "curl", args: []string{"example.com"}},
+		{name: "user subcommand", command: "gcloud compute ssh vm", rules: domain.PrefixRules{Subcommands: []string{"gcloud compute"}}, expected: "gcloud-compute", args: []string{"ssh", "vm"}},
+		{name: "no program", command: "FOO=1", expected: ""},
+		{name: "empty", command: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := domain.DetectPrefix(tt.command, tt.rules)
+			if line.Prefix != tt.expected {
+				t.Errorf("DetectPrefix(%q).Prefix = %q, want %q", tt.command, line.Prefix, tt.expected)
+			}
+			if !slices.Equal(line.Args, tt.args) {
+				t.Errorf("DetectPrefix(%q).Args = %q, want %q", tt.command, line.Args, tt.args)
+			}
+		})
+	}
+}
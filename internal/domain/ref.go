@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+const RefSep = ":"
+
+// Ref names a stored command as prefix:short. Prefixes of remote books
+// contain a colon themselves ("team:docker:up"), so references are split
+// on the last one.
+type Ref struct {
+	Prefix string
+	Short  string
+}
+
+func ParseRef(s string) (Ref, error) {
+	i := strings.LastIndex(s, RefSep)
+	if i <= 0 || i == len(s)-len(RefSep) {
+		return Ref{}, fmt.Errorf("invalid command reference '%s': use prefix%sshort", s, RefSep)
+	}
+	return Ref{Prefix: s[:i], Short: s[i+len(RefSep):]}, nil
+}
+
+func (r Ref) String() string {
+	return r.Prefix + RefSep + r.Short
+}
@@ -0,0 +1,36 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+)
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    domain.Ref
+		expectError bool
+	}{
+		{input: "docker:up", expected: domain.Ref{Prefix: "docker", Short: "up"}},
+		{input: "team:docker:up", expected: domain.Ref{Prefix: "team:docker", Short: "up"}},
+		{input: "docker", expectError: true},
+		{input: ":up", expectError: true},
+		{input: "docker:", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ref, err := domain.ParseRef(tt.input)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("ParseRef(%q) error = %v, expectError %v", tt.input, err, tt.expectError)
+			}
+			if ref != tt.expected {
+				t.Errorf("ParseRef(%q) = %+v, want %+v", tt.input, ref, tt.expected)
+			}
+			if !tt.expectError && ref.String() != tt.input {
+				t.Errorf("String() = %q, want %q", ref.String(), tt.input)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Selector picks entries of a book for bulk operations. An entry is
+// selected when it matches every field that is set.
+type Selector struct {
+	Prefix string
+	// Match is a glob pattern, where * matches any text and ? a single
+	// character, compared with the prefix:short of an entry and with its
+	// command.
+	Match string
+}
+
+func (s Selector) IsZero() bool {
+	return s.Prefix == "" && s.Match == ""
+}
+
+// Select returns the refs in commands chosen by the selector, sorted by
+// prefix and short.
+func (s Selector) Select(commands map[Ref]string) ([]Ref, error) {
+	if s.IsZero() {
+		return nil, fmt.Errorf("no entries selected: give a prefix or a pattern")
+	}
+
+	var pattern *regexp.Regexp
+	if s.Match != "" {
+		pattern = compileGlob(s.Match)
+	}
+
+	var refs []Ref
+	for ref, command := range commands {
+		if s.Prefix != "" && ref.Prefix != s.Prefix {
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(ref.String()) && !pattern.MatchString(command) {
+			continue
+		}
+		refs = append(refs, ref)
+	}
+	sortRefs(refs)
+	return refs, nil
+}
+
+// compileGlob turns a glob pattern into a regular expression matching the
+// whole of a string, across lines.
+func compileGlob(glob string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString(`(?s)^`)
+	for _, r := range glob {
+		switch r {
+		case '*':
+			expr.WriteString(`.*`)
+		case '?':
+			expr.WriteString(`.`)
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString(`$`)
+	return regexp.MustCompile(expr.String())
+}
@@ -0,0 +1,78 @@
+package domain_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+)
+
+func TestSelectorSelect(t *testing.T) {
+	commands := map[domain.Ref]string{
+		{Prefix: "kubectl", Short: "pods-staging"}: "kubectl get pods --context staging",
+		{Prefix: "kubectl", Short: "pods-prod"}:    "kubectl get pods --context prod",
+		{Prefix: "k8s", Short: "logs"}:             "kubectl logs -f deploy/api --context staging",
+		{Prefix: "old-tool", Short: "run"}:         "old-tool run",
+		{Prefix: "old-tool", Short: "build"}:       "old-tool build",
+	}
+
+	tests := []struct {
+		name          string
+		selector      domain.Selector
+		expected      []domain.Ref
+		expectedError string
+	}{
+		{
+			name:     "by prefix",
+			selector: domain.Selector{Prefix: "old-tool"},
+			expected: []domain.Ref{{Prefix: "old-tool", Short: "build"}, {Prefix: "old-tool", Short: "run"}},
+		},
+		{
+			name:     "pattern on the command",
+			selector: domain.Selector{Match: "kubectl*staging*"},
+			expected: []domain.Ref{{Prefix: "k8s", Short: "logs"}, {Prefix: "kubectl", Short: "pods-staging"}},
+		},
+		{
+			name:     "pattern on the name",
+			selector: domain.Selector{Match: "*:pods-*"},
+			expected: []domain.Ref{{Prefix: "kubectl", Short: "pods-prod"}, {Prefix: "kubectl", Short: "pods-staging"}},
+		},
+		{
+			name:     "single character wildcard",
+			selector: domain.Selector{Match: "old-tool:ru?"},
+			expected: []domain.Ref{{Prefix: "old-tool", Short: "run"}},
+		},
+		{
+			name:     "prefix and pattern together",
+			selector: domain.Selector{Prefix: "kubectl", Match: "*staging*"},
+			expected: []domain.Ref{{Prefix: "kubectl", Short: "pods-staging"}},
+		},
+		{
+			name:     "no match",
+			selector: domain.Selector{Match: "terraform*"},
+		},
+		{
+			name:          "empty selector",
+			selector:      domain.Selector{},
+			expectedError: "no entries selected: give a prefix or a pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs, err := tt.selector.Select(commands)
+			if tt.expectedError != "" {
+				if err == nil || err.Error() != tt.expectedError {
+					t.Fatalf("Select() error = %v, want %s", err, tt.expectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			if !reflect.DeepEqual(refs, tt.expected) {
+				t.Errorf("Select() = %v, want %v", refs, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxShortWords is the number of words of a command kept in a
+	// generated short name.
+	maxShortWords = 3
+	// fallbackShort is used when a command has no usable words.
+	fallbackShort = "cmd"
+)
+
+// shellOperators end the part of a command that short names are made from.
+var shellOperators = map[string]bool{
+	"|": true, "||": true, "&&": true, ";": true, "&": true,
+	">": true, ">>": true, "<": true, "<<": true, "(": true, ")": true,
+}
+
+// GenerateShort derives a short name from command, such as "push-origin-main"
+// for "git push origin main" under the prefix "git". The words naming the
+// prefix, options and the directories of paths are left out, and the result
+// is made unique among the shorts for which taken returns true.
+func GenerateShort(command, prefix string, rules PrefixRules, policy NamePolicy, taken func(string) bool) string {
+	words := shortWords(commandWords(DetectPrefix(command, rules), prefix))
+	if len(words) > maxShortWords {
+		words = words[:maxShortWords]
+	}
+
+	maxLen := policy.MaxLen
+	if maxLen <= 0 {
+		maxLen = DefaultMaxNameLen
+	}
+
+	base := ""
+	for _, word := range words {
+		next := word
+		if base != "" {
+			next = base + "-" + word
+		}
+		if utf8.RuneCountInString(next) > maxLen {
+			break
+		}
+		base = next
+	}
+	if base == "" || policy.ValidateShort(base) != nil {
+		base = fallbackShort
+	}
+	return UniqueShort(base, policy, taken)
+}
+
+// UniqueShort returns base when taken reports it as free, and otherwise
+// base with the lowest free suffix from "-2" on, shortened to fit the
+// policy.
+func UniqueShort(base string, policy NamePolicy, taken func(string) bool) string {
+	if !taken(base) {
+		return base
+	}
+
+	maxLen := policy.MaxLen
+	if maxLen <= 0 {
+		maxLen = DefaultMaxNameLen
+	}
+	for n := 2; ; n++ {
+		suffix := fmt.Sprintf("-%d", n)
+		short := truncateRunes(base, maxLen-len(suffix)) + suffix
+		if !taken(short) {
+			return short
+		}
+	}
+}
+
+// commandWords returns the words of line that describe it under prefix:
+// its arguments, preceded by the words of the program not already named by
+// the prefix. A program run without arguments keeps its name.
+func commandWords(line CommandLine, prefix string) []string {
+	for k := len(line.Program); k > 0; k-- {
+		if strings.EqualFold(sanitizeName(programName(line.Program[:k])), prefix) {
+			if k == len(line.Program) && len(line.Args) == 0 {
+				return line.Program
+			}
+			return append(line.Program[k:len(line.Program):len(line.Program)], line.Args...)
+		}
+	}
+	return append(line.Program[:len(line.Program):len(line.Program)], line.Args...)
+}
+
+// shortWords returns the lowercased words that can appear in a short name.
+func shortWords(fields []string) []string {
+	var words []string
+	for _, field := range fields {
+		if strings.HasPrefix(field, "-") || strings.Contains(field, "=") || strings.ContainsAny(field, "$`") {
+			continue
+		}
+
+		if strings.Contains(field, "/") || path.Ext(field) != "" {
+			// Keep the name of a file without its directory and extension.
+			field = strings.TrimSuffix(path.Base(field), path.Ext(field))
+		}
+		if word := sanitizeWord(field); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// firstCommandLine skips blank lines, comments and a shebang in scripts.
+func firstCommandLine(command string) string {
+	for _, line := range strings.Split(command, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
+
+// sanitizeWord lowercases word and replaces the characters not allowed in
+// names with dashes.
+func sanitizeWord(word string) string {
+	mapped := strings.Map(func(r rune) rune {
+		if isNameChar(r) {
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, word)
+	return strings.Trim(mapped, "-_")
+}
+
+func truncateRunes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	if len(runes) > n {
+		return strings.TrimRight(string(runes[:n]), "-_")
+	}
+	return s
+}
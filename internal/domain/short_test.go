@@ -0,0 +1,52 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+)
+
+func TestGenerateShort(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		prefix   string
+		policy   domain.NamePolicy
+		taken    []string
+		expected string
+	}{
+		{name: "words after the prefix", command: "git push origin main", prefix: "git", expected: "push-origin-main"},
+		{name: "options and assignments skipped", command: "FOO=1 docker compose --build up", prefix: "docker", expected: "compose-up"},
+		{name: "stops at shell operators", command: "make build && make test", prefix: "make", expected: "build"},
+		{name: "file names without directory or extension", command: "kubectl apply -f k8s/prod.yaml", prefix: "kubectl", expected: "apply-prod"},
+		{name: "prefix different from the command", command: "terraform plan", prefix: "infra", expected: "terraform-plan"},
+		{name: "single word", command: "ls", prefix: "ls", expected: "ls"},
+		{name: "lowercased and sanitized", command: "npm run Build:Prod", prefix: "npm", expected: "run-build-prod"},
+		{name: "first line of a script", command: "#!/bin/sh\n# deploy\nrsync -a dist/ host:/srv\n", prefix: "deploy", expected: "rsync-dist-srv"},
+		{name: "fits the maximum length", command: "git push origin main", prefix: "git", policy: domain.NamePolicy{MaxLen: 12}, expected: "push-origin"},
+		{name: "falls back without usable words", command: "$EDITOR", prefix: "edit", expected: "cmd"},
+		{name: "made unique", command: "git push origin main", prefix: "git", taken: []string{"push-origin-main", "push-origin-main-2"}, expected: "push-origin-main-3"},
+		{name: "made unique within the maximum length", command: "git push origin main", prefix: "git", policy: domain.NamePolicy{MaxLen: 11}, taken: []string{"push-origin"}, expected: "push-orig-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taken := func(s string) bool {
+				for _, name := range tt.taken {
+					if name == s {
+						return true
+					}
+				}
+				return false
+			}
+
+			short := domain.GenerateShort(tt.command, tt.prefix, domain.PrefixRules{}, tt.policy, taken)
+			if short != tt.expected {
+				t.Errorf("GenerateShort(%q) = %q, want %q", tt.command, short, tt.expected)
+			}
+			if err := tt.policy.ValidateShort(short); err != nil {
+				t.Errorf("generated short is invalid: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// Distance returns the optimal string alignment distance between a and b,
+// counted in runes: the Levenshtein distance where swapping two adjacent
+// runes, a common typo, counts as a single edit.
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
+
+// Suggest returns the refs whose name is close enough to input to be a
+// likely misspelling of it, closest first. Refs at the same distance keep
+// their order. name picks the part of a ref compared with input.
+func Suggest(input string, refs []Ref, name func(Ref) string) []Ref {
+	// Allow roughly one typo per three characters, and always at least one.
+	maxDistance := max(1, utf8.RuneCountInString(input)/3)
+
+	type match struct {
+		ref      Ref
+		distance int
+	}
+	var matches []match
+	for _, ref := range refs {
+		if d := Distance(input, name(ref)); d <= maxDistance {
+			matches = append(matches, match{ref: ref, distance: d})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].distance < matches[j].distance })
+
+	suggestions := make([]Ref, len(matches))
+	for i, m := range matches {
+		suggestions[i] = m.ref
+	}
+	return suggestions
+}
@@ -0,0 +1,61 @@
+package domain_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "", b: "", expected: 0},
+		{a: "push", b: "", expected: 4},
+		{a: "push", b: "push", expected: 0},
+		{a: "push-mian", b: "push-main", expected: 1},
+		{a: "fial", b: "fail", expected: 1},
+		{a: "ab", b: "ba", expected: 1},
+		{a: "kitten", b: "sitting", expected: 3},
+		{a: "héllo", b: "hello", expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := domain.Distance(tt.a, tt.b); got != tt.expected {
+				t.Errorf("Distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	refs := []domain.Ref{
+		{Prefix: "git", Short: "push-main"},
+		{Prefix: "git", Short: "pull"},
+		{Prefix: "gh", Short: "push-mai"},
+		{Prefix: "docker", Short: "up"},
+		{Prefix: "test", Short: "fail"},
+	}
+	short := func(ref domain.Ref) string { return ref.Short }
+
+	tests := []struct {
+		input    string
+		expected []domain.Ref
+	}{
+		{input: "push-mian", expected: []domain.Ref{refs[0], refs[2]}},
+		{input: "pul", expected: []domain.Ref{refs[1]}},
+		{input: "fial", expected: []domain.Ref{refs[4]}},
+		{input: "deploy", expected: []domain.Ref{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := domain.Suggest(tt.input, refs, short); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Suggest(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// templateVar matches placeholders such as {{env}}. Go templates as in
+// docker's --format '{{.State}}' are left alone, their fields starting
+// with a dot.
+var templateVar = regexp.MustCompile(`\{\{([A-Za-z_][A-Za-z0-9_-]*)\}\}`)
+
+// TemplateVars returns the names of the placeholders in command, in the
+// order they first appear.
+func TemplateVars(command string) []string {
+	var names []string
+	for _, match := range templateVar.FindAllStringSubmatch(command, -1) {
+		if !slices.Contains(names, match[1]) {
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// RenderTemplate replaces the placeholders in command with their values.
+// Every placeholder needs a value; values for names that do not appear are
+// ignored.
+func RenderTemplate(command string, values map[string]string) (string, error) {
+	var missing []string
+	for _, name := range TemplateVars(command) {
+		if _, ok := values[name]; !ok {
+			missing = append(missing, "{{"+name+"}}")
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("no value for %s", strings.Join(missing, ", "))
+	}
+
+	return templateVar.ReplaceAllStringFunc(command, func(placeholder string) string {
+		return values[templateVar.FindStringSubmatch(placeholder)[1]]
+	}), nil
+}
@@ -0,0 +1,88 @@
+package domain_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+)
+
+func TestTemplateVars(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected []string
+	}{
+		{command: "kubectl get pods", expected: nil},
+		{command: "kubectl --context {{env}} logs {{pod}}", expected: []string{"env", "pod"}},
+		{command: "ssh {{host}} && scp a {{host}}:b", expected: []string{"host"}},
+		{command: "docker inspect --format '{{.State.Status}}' {{name}}", expected: []string{"name"}},
+		{command: "echo {{ spaced }} {{}}", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if got := domain.TemplateVars(tt.command); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("TemplateVars(%q) = %v, want %v", tt.command, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	tests := []struct {
+		name          string
+		command       string
+		values        map[string]string
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "no placeholders",
+			command:  "git status",
+			values:   map[string]string{"env": "prod"},
+			expected: "git status",
+		},
+		{
+			name:     "every placeholder replaced",
+			command:  "kubectl --context {{env}} logs {{pod}} -n {{env}}",
+			values:   map[string]string{"env": "prod", "pod": "web-0"},
+			expected: "kubectl --context prod logs web-0 -n prod",
+		},
+		{
+			name:     "empty value",
+			command:  "make {{target}}",
+			values:   map[string]string{"target": ""},
+			expected: "make ",
+		},
+		{
+			name:          "missing values",
+			command:       "kubectl --context {{env}} logs {{pod}}",
+			values:        map[string]string{"pod": "web-0", "other": "x"},
+			expectedError: "no value for {{env}}",
+		},
+		{
+			name:          "several missing values",
+			command:       "scp {{src}} {{host}}:{{dst}}",
+			values:        nil,
+			expectedError: "no value for {{src}}, {{host}}, {{dst}}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := domain.RenderTemplate(tt.command, tt.values)
+			if tt.expectedError != "" {
+				if err == nil || err.Error() != tt.expectedError {
+					t.Fatalf("RenderTemplate() error = %v, want %s", err, tt.expectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderTemplate() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("RenderTemplate() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
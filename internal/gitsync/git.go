@@ -0,0 +1,63 @@
+package gitsync
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const (
+	fallbackUserName  = "cmdbook"
+	fallbackUserEmail = "cmdbook@localhost"
+)
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", subcommand(args), msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// gitWithIdentity runs a git command that records commits, falling back to
+// a cmdbook identity when the user has not configured one.
+func gitWithIdentity(dir string, args ...string) (string, error) {
+	if email, _ := git(dir, "config", "user.email"); email == "" {
+		args = append([]string{"-c", "user.name=" + fallbackUserName, "-c", "user.email=" + fallbackUserEmail}, args...)
+	}
+	return git(dir, args...)
+}
+
+func subcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-c" {
+			i++
+			continue
+		}
+		return args[i]
+	}
+	return ""
+}
+
+func currentBranch(dir string) (string, error) {
+	return git(dir, "symbolic-ref", "--short", "HEAD")
+}
+
+func hasRemote(dir, name string) bool {
+	_, err := git(dir, "remote", "get-url", name)
+	return err == nil
+}
+
+func isBareRepository(path string) bool {
+	out, err := git(path, "rev-parse", "--is-bare-repository")
+	return err == nil && out == "true"
+}
@@ -0,0 +1,27 @@
+package gitsync_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+)
+
+const mergeDriverEnv = "CMDBOOK_TEST_MERGE_DRIVER"
+
+// TestMain lets the test binary act as the git merge driver of synced books.
+func TestMain(m *testing.M) {
+	if os.Getenv(mergeDriverEnv) != "" {
+		if err := handler.MergeBooks(os.Args[1], os.Args[2], os.Args[3]); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func testMergeDriver() string {
+	return mergeDriverEnv + "=1 '" + os.Args[0] + "'"
+}
@@ -0,0 +1,291 @@
+package gitsync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+)
+
+const (
+	BookFile   = "cmdbook.toml"
+	RemoteName = "origin"
+
+	cloneDirName    = "sync"
+	attributesFile  = ".gitattributes"
+	mergeDriverName = "cmdbook"
+)
+
+var ErrNotSynced = errors.New("command book is not synced; run 'cb sync init' first")
+
+// Init moves the book at configPath into a git repository and replaces
+// configPath with a symlink to it. target is either a local directory or a
+// remote to clone; remote optionally sets the origin of a local directory.
+// When mergeDriver is set, it is registered as the merge driver of the book.
+func Init(configPath, target, remote, mergeDriver string) (string, error) {
+	if _, ok := RepoDir(configPath); ok {
+		return "", fmt.Errorf("command book is already synced: %s", configPath)
+	}
+
+	repoDir, err := prepareRepo(configPath, target, remote)
+	if err != nil {
+		return "", err
+	}
+
+	if mergeDriver != "" {
+		if err := installMergeDriver(repoDir, mergeDriver); err != nil {
+			return "", err
+		}
+	}
+
+	bookPath := filepath.Join(repoDir, BookFile)
+	if err := mergeLocalBook(configPath, bookPath); err != nil {
+		return "", err
+	}
+
+	if err := os.Remove(configPath); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to replace %s: %w", configPath, err)
+	}
+	if err := os.Symlink(bookPath, configPath); err != nil {
+		return "", fmt.Errorf("failed to link %s: %w", configPath, err)
+	}
+
+	if err := Commit(configPath, "sync init"); err != nil {
+		return "", err
+	}
+	return repoDir, nil
+}
+
+// RepoDir reports the repository holding the book when configPath is synced.
+func RepoDir(configPath string) (string, bool) {
+	info, err := os.Lstat(configPath)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return "", false
+	}
+
+	bookPath, err := filepath.EvalSymlinks(configPath)
+	if err != nil {
+		return "", false
+	}
+
+	dir := filepath.Dir(bookPath)
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return "", false
+	}
+	return dir, true
+}
+
+// Commit records the current book with message. It is a no-op for books
+// that are not synced or have no pending changes.
+func Commit(configPath, message string) error {
+	dir, ok := RepoDir(configPath)
+	if !ok {
+		return nil
+	}
+
+	paths := []string{BookFile}
+	if _, err := os.Stat(filepath.Join(dir, attributesFile)); err == nil {
+		paths = append(paths, attributesFile)
+	}
+
+	if _, err := git(dir, append([]string{"add", "--"}, paths...)...); err != nil {
+		return err
+	}
+	if _, err := git(dir, "diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+	_, err := gitWithIdentity(dir, "commit", "--quiet", "-m", message)
+	return err
+}
+
+func Pull(configPath string) error {
+	dir, err := syncedRepo(configPath)
+	if err != nil {
+		return err
+	}
+
+	branch, err := currentBranch(dir)
+	if err != nil {
+		return err
+	}
+
+	if _, err := git(dir, "fetch", "--quiet", RemoteName); err != nil {
+		return err
+	}
+
+	remoteRef := RemoteName + "/" + branch
+	if _, err := git(dir, "rev-parse", "--verify", "--quiet", remoteRef); err != nil {
+		return nil
+	}
+
+	if _, err := gitWithIdentity(dir, "merge", "--no-edit", "--allow-unrelated-histories", remoteRef); err != nil {
+		return fmt.Errorf("merge with %s failed, resolve it in %s: %w", remoteRef, dir, err)
+	}
+	return nil
+}
+
+func Push(configPath string) error {
+	dir, err := syncedRepo(configPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = git(dir, "push", "--quiet", "--set-upstream", RemoteName, "HEAD")
+	return err
+}
+
+func syncedRepo(configPath string) (string, error) {
+	dir, ok := RepoDir(configPath)
+	if !ok {
+		return "", ErrNotSynced
+	}
+	if !hasRemote(dir, RemoteName) {
+		return "", fmt.Errorf("no remote configured for %s", dir)
+	}
+	return dir, nil
+}
+
+func prepareRepo(configPath, target, remote string) (string, error) {
+	if isRemote(target) {
+		if remote != "" {
+			return "", fmt.Errorf("cannot set a remote when cloning from %s", target)
+		}
+		return cloneRepo(configPath, target)
+	}
+
+	repoDir, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(repoDir, 0755); err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(filepath.Join(repoDir, ".git")); os.IsNotExist(err) {
+		if _, err := git(repoDir, "init", "--quiet"); err != nil {
+			return "", err
+		}
+	}
+
+	if remote != "" {
+		if _, err := git(repoDir, "remote", "add", RemoteName, remote); err != nil {
+			return "", err
+		}
+	}
+	return repoDir, nil
+}
+
+func cloneRepo(configPath, remote string) (string, error) {
+	repoDir := filepath.Join(config.DataDir(configPath), cloneDirName)
+	if _, err := os.Stat(repoDir); err == nil {
+		return "", fmt.Errorf("sync directory already exists: %s", repoDir)
+	}
+	if err := os.MkdirAll(filepath.Dir(repoDir), 0755); err != nil {
+		return "", err
+	}
+
+	if _, err := git(filepath.Dir(repoDir), "clone", "--quiet", remote, repoDir); err != nil {
+		return "", err
+	}
+	return repoDir, nil
+}
+
+// mergeLocalBook copies the local book into the synced book. Entries,
+// remotes and trusted keys already present in the synced book take
+// precedence; the local settings are kept when the synced book has none,
+// and local risk rules are added to the synced ones.
+func mergeLocalBook(configPath, bookPath string) error {
+	local, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	synced, err := config.LoadConfig(bookPath)
+	if err != nil {
+		return fmt.Errorf("failed to load synced book: %w", err)
+	}
+
+	for prefix, cmds := range local.Commands {
+		for short := range cmds {
+			if _, exists := synced.GetEntry(prefix, short); exists {
+				continue
+			}
+			entry, _ := local.GetEntry(prefix, short)
+			synced.SetEntry(prefix, short, entry)
+		}
+	}
+
+	for name, r := range local.Remotes {
+		if _, exists := synced.Remotes[name]; !exists {
+			if synced.Remotes == nil {
+				synced.Remotes = make(map[string]config.Remote)
+			}
+			synced.Remotes[name] = r
+		}
+	}
+
+	for name, key := range local.TrustedKeys {
+		if _, exists := synced.TrustedKeys[name]; !exists {
+			if synced.TrustedKeys == nil {
+				synced.TrustedKeys = make(map[string]string)
+			}
+			synced.TrustedKeys[name] = key
+		}
+	}
+
+	for _, rule := range local.RiskRules {
+		if !slices.Contains(synced.RiskRules, rule) {
+			synced.RiskRules = append(synced.RiskRules, rule)
+		}
+	}
+
+	if synced.Settings.IsZero() {
+		synced.Settings = local.Settings
+	}
+
+	return config.SaveConfig(synced, bookPath)
+}
+
+// installMergeDriver routes merges of the book through command, which is
+// invoked by git as "<command> %O %A %B".
+func installMergeDriver(repoDir, command string) error {
+	if _, err := git(repoDir, "config", "merge."+mergeDriverName+".name", "cmdbook entry-level merge"); err != nil {
+		return err
+	}
+	if _, err := git(repoDir, "config", "merge."+mergeDriverName+".driver", command+" %O %A %B"); err != nil {
+		return err
+	}
+
+	path := filepath.Join(repoDir, attributesFile)
+	line := BookFile + " merge=" + mergeDriverName
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, existing := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(existing) == line {
+			return nil
+		}
+	}
+
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		data = append(data, '\n')
+	}
+	data = append(data, line+"\n"...)
+	return os.WriteFile(path, data, 0644)
+}
+
+func isRemote(target string) bool {
+	if strings.Contains(target, "://") {
+		return true
+	}
+	if at, colon := strings.Index(target, "@"), strings.Index(target, ":"); at > 0 && colon > at {
+		return true
+	}
+	return isBareRepository(target)
+}
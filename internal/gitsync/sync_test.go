@@ -0,0 +1,272 @@
+package gitsync_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/gitsync"
+)
+
+func requireGit(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func newBareRemote(t *testing.T) string {
+	t.Helper()
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	runGit(t, filepath.Dir(remote), "init", "--quiet", "--bare", remote)
+	return remote
+}
+
+func writeBook(t *testing.T, path string, commands map[string]map[string]string) {
+	t.Helper()
+	if err := config.SaveConfig(&config.Config{Commands: commands}, path); err != nil {
+		t.Fatalf("failed to write book: %v", err)
+	}
+}
+
+func loadBook(t *testing.T, path string) *config.Config {
+	t.Helper()
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load book: %v", err)
+	}
+	return cfg
+}
+
+func TestInitLocalDirectory(t *testing.T) {
+	requireGit(t)
+
+	configPath := filepath.Join(t.TempDir(), "book.toml")
+	writeBook(t, configPath, map[string]map[string]string{"git": {"st": "git status"}})
+
+	repoDir := filepath.Join(t.TempDir(), "repo")
+	got, err := gitsync.Init(configPath, repoDir, "", "")
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if got != repoDir {
+		t.Errorf("repo dir = %q, want %q", got, repoDir)
+	}
+
+	target, err := os.Readlink(configPath)
+	if err != nil {
+		t.Fatalf("config path is not a symlink: %v", err)
+	}
+	if target != filepath.Join(repoDir, gitsync.BookFile) {
+		t.Errorf("symlink target = %q", target)
+	}
+
+	if cfg := loadBook(t, configPath); cfg.Commands["git"]["st"] != "git status" {
+		t.Errorf("book lost its entries: %v", cfg.Commands)
+	}
+	if log := runGit(t, repoDir, "log", "--format=%s"); log != "sync init" {
+		t.Errorf("log = %q, want %q", log, "sync init")
+	}
+
+	if _, err := gitsync.Init(configPath, repoDir, "", ""); err == nil {
+		t.Error("expected error when initializing twice")
+	}
+	if err := gitsync.Pull(configPath); err == nil || !strings.Contains(err.Error(), "no remote configured") {
+		t.Errorf("Pull() error = %v, want missing remote", err)
+	}
+}
+
+func TestInitKeepsBookSections(t *testing.T) {
+	requireGit(t)
+
+	configPath := filepath.Join(t.TempDir(), "book.toml")
+	local := &config.Config{
+		Commands: map[string]map[string]string{"git": {"st": "git status"}},
+		Remotes: map[string]config.Remote{
+			"team": {URL: "https://example.com/team.toml", Key: "alice"},
+			"ops":  {URL: "https://example.com/ops.toml", RequireSignature: true},
+		},
+		TrustedKeys: map[string]string{"alice": "AAAA"},
+		RiskRules:   []config.RiskRule{{Pattern: "helmfile .*sync", Level: "high"}},
+		Settings:    config.Settings{Shell: "bash"},
+	}
+	if err := config.SaveConfig(local, configPath); err != nil {
+		t.Fatalf("failed to write book: %v", err)
+	}
+
+	// The synced book has its own remote under the same name and a rule
+	// of its own; both are kept next to the local additions.
+	repoDir := filepath.Join(t.TempDir(), "repo")
+	synced := &config.Config{
+		Commands:  map[string]map[string]string{"make": {"b": "make build"}},
+		Remotes:   map[string]config.Remote{"team": {URL: "https://example.com/shared.toml"}},
+		RiskRules: []config.RiskRule{{Pattern: "terraform destroy", Level: "high"}},
+	}
+	if err := os.MkdirAll(repoDir, 0755); err != nil {
+		t.Fatalf("failed to create repo dir: %v", err)
+	}
+	if err := config.SaveConfig(synced, filepath.Join(repoDir, gitsync.BookFile)); err != nil {
+		t.Fatalf("failed to write synced book: %v", err)
+	}
+
+	if _, err := gitsync.Init(configPath, repoDir, "", ""); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	cfg := loadBook(t, configPath)
+	if cfg.Commands["git"]["st"] != "git status" || cfg.Commands["make"]["b"] != "make build" {
+		t.Errorf("commands = %v", cfg.Commands)
+	}
+	if cfg.Remotes["team"].URL != "https://example.com/shared.toml" || !cfg.Remotes["ops"].RequireSignature {
+		t.Errorf("remotes = %v, want the synced team remote", cfg.Remotes)
+	}
+	if cfg.TrustedKeys["alice"] != "AAAA" {
+		t.Errorf("trusted keys = %v", cfg.TrustedKeys)
+	}
+	if len(cfg.RiskRules) != 2 || cfg.RiskRules[1].Pattern != "helmfile .*sync" {
+		t.Errorf("risk rules = %v", cfg.RiskRules)
+	}
+	if cfg.Settings.Shell != "bash" {
+		t.Errorf("settings = %+v, want the local settings", cfg.Settings)
+	}
+}
+
+func TestCommit(t *testing.T) {
+	requireGit(t)
+
+	t.Run("not synced is a no-op", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "book.toml")
+		writeBook(t, configPath, map[string]map[string]string{})
+
+		if err := gitsync.Commit(configPath, "add git st"); err != nil {
+			t.Errorf("Commit() error = %v", err)
+		}
+	})
+
+	t.Run("records one commit per change", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "book.toml")
+		repoDir := filepath.Join(t.TempDir(), "repo")
+		if _, err := gitsync.Init(configPath, repoDir, "", ""); err != nil {
+			t.Fatalf("Init() error = %v", err)
+		}
+
+		writeBook(t, configPath, map[string]map[string]string{"git": {"push-main": "git push origin main"}})
+		if err := gitsync.Commit(configPath, "add git push-main"); err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+		if err := gitsync.Commit(configPath, "nothing changed"); err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+
+		log := runGit(t, repoDir, "log", "--format=%s")
+		if log != "add git push-main\nsync init" {
+			t.Errorf("log = %q", log)
+		}
+	})
+}
+
+func TestPushPull(t *testing.T) {
+	requireGit(t)
+
+	remote := newBareRemote(t)
+
+	pathA := filepath.Join(t.TempDir(), "book.toml")
+	writeBook(t, pathA, map[string]map[string]string{"git": {"st": "git status"}})
+	if _, err := gitsync.Init(pathA, remote, "", ""); err != nil {
+		t.Fatalf("Init(A) error = %v", err)
+	}
+	if err := gitsync.Push(pathA); err != nil {
+		t.Fatalf("Push(A) error = %v", err)
+	}
+
+	pathB := filepath.Join(t.TempDir(), "book.toml")
+	writeBook(t, pathB, map[string]map[string]string{"docker": {"up": "docker compose up"}})
+	if _, err := gitsync.Init(pathB, remote, "", ""); err != nil {
+		t.Fatalf("Init(B) error = %v", err)
+	}
+
+	cfgB := loadBook(t, pathB)
+	if cfgB.Commands["git"]["st"] != "git status" || cfgB.Commands["docker"]["up"] != "docker compose up" {
+		t.Fatalf("book B was not merged with the remote: %v", cfgB.Commands)
+	}
+	if err := gitsync.Push(pathB); err != nil {
+		t.Fatalf("Push(B) error = %v", err)
+	}
+
+	if err := gitsync.Pull(pathA); err != nil {
+		t.Fatalf("Pull(A) error = %v", err)
+	}
+	if cfgA := loadBook(t, pathA); cfgA.Commands["docker"]["up"] != "docker compose up" {
+		t.Errorf("book A did not receive B's entry: %v", cfgA.Commands)
+	}
+}
+
+func TestNotSynced(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "book.toml")
+
+	if err := gitsync.Pull(configPath); err != gitsync.ErrNotSynced {
+		t.Errorf("Pull() error = %v, want %v", err, gitsync.ErrNotSynced)
+	}
+	if err := gitsync.Push(configPath); err != gitsync.ErrNotSynced {
+		t.Errorf("Push() error = %v, want %v", err, gitsync.ErrNotSynced)
+	}
+}
+
+func TestPullMergesEntries(t *testing.T) {
+	requireGit(t)
+
+	remote := newBareRemote(t)
+	base := map[string]map[string]string{"git": {"st": "git status", "co": "git checkout"}}
+
+	pathA := filepath.Join(t.TempDir(), "book.toml")
+	writeBook(t, pathA, base)
+	repoA, err := gitsync.Init(pathA, remote, "", testMergeDriver())
+	if err != nil {
+		t.Fatalf("Init(A) error = %v", err)
+	}
+	if err := gitsync.Push(pathA); err != nil {
+		t.Fatalf("Push(A) error = %v", err)
+	}
+
+	pathB := filepath.Join(t.TempDir(), "book.toml")
+	if _, err := gitsync.Init(pathB, remote, "", testMergeDriver()); err != nil {
+		t.Fatalf("Init(B) error = %v", err)
+	}
+
+	writeBook(t, pathA, map[string]map[string]string{"git": {"st": "git status -s", "co": "git checkout"}})
+	if err := gitsync.Commit(pathA, "update git st"); err != nil {
+		t.Fatalf("Commit(A) error = %v", err)
+	}
+
+	writeBook(t, pathB, map[string]map[string]string{"git": {"st": "git status", "co": "git checkout", "lg": "git log"}})
+	if err := gitsync.Commit(pathB, "add git lg"); err != nil {
+		t.Fatalf("Commit(B) error = %v", err)
+	}
+	if err := gitsync.Push(pathB); err != nil {
+		t.Fatalf("Push(B) error = %v", err)
+	}
+
+	if err := gitsync.Pull(pathA); err != nil {
+		t.Fatalf("Pull(A) error = %v", err)
+	}
+
+	cfg := loadBook(t, pathA)
+	if cfg.Commands["git"]["st"] != "git status -s" || cfg.Commands["git"]["lg"] != "git log" || cfg.Commands["git"]["co"] != "git checkout" {
+		t.Errorf("unexpected merge result: %v", cfg.Commands)
+	}
+	if attrs, err := os.ReadFile(filepath.Join(repoA, ".gitattributes")); err != nil || !strings.Contains(string(attrs), "cmdbook.toml merge=cmdbook") {
+		t.Errorf(".gitattributes = %q, %v", attrs, err)
+	}
+}
@@ -2,41 +2,160 @@ package handler
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/pHo9UBenaA/cmdbook/internal/config"
-	"github.com/pHo9UBenaA/cmdbook/internal/constant"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/pkg/ioutil"
 )
 
-func AddCommand(configPath, prefix, short, command string) error {
+type AddOptions struct {
+	// Dir is the working directory the command runs in.
+	Dir string
+	// Env holds KEY=VALUE assignments applied when the command runs.
+	Env []string
+	// Shell runs the command instead of the default shell.
+	Shell string
+	// Steps makes the entry a workflow of other entries, given as
+	// prefix:short[@condition]; the command must then be empty.
+	Steps []string
+	// Yes saves a generated short name without asking to confirm it.
+	Yes bool
+	// Force replaces an existing entry with the same prefix and short.
+	Force bool
+	// Input is read for the confirmation; it defaults to os.Stdin, where
+	// the question is only asked on a terminal.
+	Input io.Reader
+}
+
+// namePolicy returns the naming rules of the book.
+func namePolicy(cfg *config.Config) domain.NamePolicy {
+	return domain.NamePolicy{MaxLen: cfg.Settings.MaxNameLength}
+}
+
+// prefixRules returns the user's additions to the prefix detection.
+func prefixRules(cfg *config.Config) domain.PrefixRules {
+	return domain.PrefixRules{Wrappers: cfg.Settings.PrefixWrappers, Subcommands: cfg.Settings.PrefixSubcommands}
+}
+
+func AddCommand(configPath, prefix, short, command string, opts AddOptions) error {
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
 		return err
 	}
 
+	env, err := parseEnv(opts.Env)
+	if err != nil {
+		return err
+	}
+
+	steps, err := parseSteps(opts.Steps)
+	if err != nil {
+		return err
+	}
+	if len(steps) > 0 {
+		if command != "" {
+			return fmt.Errorf("a workflow takes steps instead of a command")
+		}
+		if prefix == "" {
+			return fmt.Errorf("a prefix is required for workflows")
+		}
+		command = describeSteps(steps)
+	}
+
+	if prefix == "" && strings.Contains(command, "\n") {
+		return fmt.Errorf("a prefix is required for multi-line commands")
+	}
+
+	policy := namePolicy(cfg)
+	rules := prefixRules(cfg)
 	if prefix == "" {
-		prefix = strings.SplitN(command, " ", 2)[0]
+		prefix = domain.DetectPrefix(command, rules).Prefix
+		if prefix == "" {
+			return fmt.Errorf("could not detect a prefix; set one with --prefix")
+		}
+		if err := policy.ValidatePrefix(prefix); err != nil {
+			return fmt.Errorf("%w (taken from the command; set one with --prefix)", err)
+		}
+	} else if err := policy.ValidatePrefix(prefix); err != nil {
+		return err
 	}
 
 	if short == "" {
-		existing := cfg.Commands[prefix]
-		nextIndex := len(existing)
-		short = fmt.Sprintf("cmd%d", nextIndex)
+		short = domain.GenerateShort(command, prefix, rules, policy, func(s string) bool {
+			_, exists := cfg.Commands[prefix][s]
+			return exists
+		})
+		if !opts.Yes {
+			if short, err = confirmShort(prefix, short, opts.Input); err != nil {
+				return err
+			}
+		}
 	}
 
-	if len(short) > constant.MaxShortLen {
-		return fmt.Errorf("short name '%s' exceeds maximum length of 20 characters", short)
+	if err := policy.ValidateShort(short); err != nil {
+		return err
 	}
 
-	if cfg.Commands[prefix] == nil {
-		cfg.Commands[prefix] = make(map[string]string)
+	if existing, ok := cfg.Commands[prefix][short]; ok && !opts.Force {
+		return fmt.Errorf("%s %s already exists (%s); use --force to replace it", prefix, short, ioutil.FirstLine(existing))
+	}
+	if len(steps) == 0 {
+		if refs := sameCommand(cfg, domain.Ref{Prefix: prefix, Short: short}, command); len(refs) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: the same command is already saved as %s\n", formatCandidates(refs))
+		}
 	}
 
-	cfg.Commands[prefix][short] = command
-	if err := config.SaveConfig(cfg, configPath); err != nil {
+	cfg.SetEntry(prefix, short, config.Entry{
+		Command: command,
+		Meta:    config.EntryMeta{Dir: opts.Dir, Env: env, Shell: opts.Shell, Steps: steps},
+	})
+	if err := saveConfig(cfg, configPath, fmt.Sprintf("add %s %s", prefix, short)); err != nil {
 		return err
 	}
 
 	fmt.Printf("Added: %s %s -> %s ", prefix, short, command)
 	return nil
 }
+
+// sameCommand returns the commands of the book other than ref that run
+// command, ignoring differences in whitespace. Workflows are left out.
+func sameCommand(cfg *config.Config, ref domain.Ref, command string) []domain.Ref {
+	normalized := domain.NormalizeCommand(command)
+	var refs []domain.Ref
+	for prefix, cmds := range cfg.Commands {
+		for short, other := range cmds {
+			candidate := domain.Ref{Prefix: prefix, Short: short}
+			if candidate == ref || domain.NormalizeCommand(other) != normalized {
+				continue
+			}
+			if entry, _ := cfg.GetEntry(prefix, short); !entry.IsWorkflow() {
+				refs = append(refs, candidate)
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+	return refs
+}
+
+// confirmShort offers the generated short name for confirmation; an
+// answer other than an empty line replaces it.
+func confirmShort(prefix, short string, input io.Reader) (string, error) {
+	input, ok := promptInput(input)
+	if !ok {
+		return short, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Short name for %s [%s]: ", prefix, short)
+	answer, err := readLine(input)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read short name: %w", err)
+	}
+	if answer = strings.TrimSpace(answer); answer != "" {
+		return answer, nil
+	}
+	return short, nil
+}
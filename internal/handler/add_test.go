@@ -1,7 +1,9 @@
 package handler_test
 
 import (
+	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/pHo9UBenaA/cmdbook/internal/config"
@@ -16,6 +18,8 @@ func TestAddCommand(t *testing.T) {
 		prefix        string
 		short         string
 		command       string
+		input         string
+		force         bool
 		expectedError error
 		expectedCmds  map[string]map[string]string
 	}{
@@ -51,11 +55,85 @@ func TestAddCommand(t *testing.T) {
 			command: "echo Hello",
 			expectedCmds: map[string]map[string]string{
 				"testPrefix": {
-					"cmd0": "some command",
-					"cmd1": "echo Hello",
+					"cmd0":       "some command",
+					"echo-hello": "echo Hello",
 				},
 			},
 		},
+		{
+			name: "Generated short is derived from the command and kept unique",
+			initialConfig: &config.Config{
+				Commands: map[string]map[string]string{
+					"git": {"push-origin-main": "git push origin main"},
+				},
+			},
+			prefix:  "git",
+			command: "git push --force origin main",
+			expectedCmds: map[string]map[string]string{
+				"git": {
+					"push-origin-main":   "git push origin main",
+					"push-origin-main-2": "git push --force origin main",
+				},
+			},
+		},
+		{
+			name:          "Generated short can be replaced when confirming it",
+			initialConfig: &config.Config{Commands: map[string]map[string]string{}},
+			prefix:        "git",
+			command:       "git push origin main",
+			input:         "pm\n",
+			expectedCmds: map[string]map[string]string{
+				"git": {"pm": "git push origin main"},
+			},
+		},
+		{
+			name:          "Generated short is kept on an empty answer",
+			initialConfig: &config.Config{Commands: map[string]map[string]string{}},
+			prefix:        "kubectl",
+			command:       "kubectl apply -f k8s/prod.yaml",
+			input:         "\n",
+			expectedCmds: map[string]map[string]string{
+				"kubectl": {"apply-prod": "kubectl apply -f k8s/prod.yaml"},
+			},
+		},
+		{
+			name: "Refuse to replace an existing command",
+			initialConfig: &config.Config{
+				Commands: map[string]map[string]string{"git": {"pm": "git push origin main"}},
+			},
+			prefix:        "git",
+			short:         "pm",
+			command:       "git push origin master",
+			expectedError: errors.New("git pm already exists (git push origin main); use --force to replace it"),
+			expectedCmds: map[string]map[string]string{
+				"git": {"pm": "git push origin main"},
+			},
+		},
+		{
+			name: "Replace an existing command with force",
+			initialConfig: &config.Config{
+				Commands: map[string]map[string]string{"git": {"pm": "git push origin main"}},
+			},
+			prefix:  "git",
+			short:   "pm",
+			command: "git push origin master",
+			force:   true,
+			expectedCmds: map[string]map[string]string{
+				"git": {"pm": "git push origin master"},
+			},
+		},
+		{
+			name: "Save a command that is already saved elsewhere",
+			initialConfig: &config.Config{
+				Commands: map[string]map[string]string{"git": {"pm": "git push origin main"}},
+			},
+			prefix:  "git",
+			short:   "push-main",
+			command: "git  push origin main",
+			expectedCmds: map[string]map[string]string{
+				"git": {"pm": "git push origin main", "push-main": "git  push origin main"},
+			},
+		},
 		{
 			name:          "Add command to non-existent config file",
 			initialConfig: nil,
@@ -66,6 +144,70 @@ func TestAddCommand(t *testing.T) {
 				"newPrefix": {"newShort": "new command"},
 			},
 		},
+		{
+			name:          "Reject a short name with a space",
+			initialConfig: &config.Config{Commands: map[string]map[string]string{}},
+			prefix:        "git",
+			short:         "push main",
+			command:       "git push origin main",
+			expectedError: errors.New("invalid short name 'push main': a space is not allowed; use letters, digits, '-' or '_'"),
+			expectedCmds:  map[string]map[string]string{},
+		},
+		{
+			name:          "Prefix is detected past wrappers and assignments",
+			initialConfig: &config.Config{Commands: map[string]map[string]string{}},
+			command:       "sudo DEBIAN_FRONTEND=noninteractive apt update",
+			expectedCmds: map[string]map[string]string{
+				"apt": {"update": "sudo DEBIAN_FRONTEND=noninteractive apt update"},
+			},
+		},
+		{
+			name:          "Prefix of a script is its base name",
+			initialConfig: &config.Config{Commands: map[string]map[string]string{}},
+			command:       "cd app && ./scripts/deploy.sh prod",
+			expectedCmds: map[string]map[string]string{
+				"deploy": {"prod": "cd app && ./scripts/deploy.sh prod"},
+			},
+		},
+		{
+			name: "Prefix rules are configurable",
+			initialConfig: &config.Config{
+				Commands: map[string]map[string]string{},
+				Settings: config.Settings{PrefixWrappers: []string{"proxychains"}, PrefixSubcommands: []string{"gcloud compute"}},
+			},
+			command: "proxychains gcloud compute instances list",
+			expectedCmds: map[string]map[string]string{
+				"gcloud-compute": {"instances-list": "proxychains gcloud compute instances list"},
+			},
+		},
+		{
+			name:          "Reject a prefix taken from the command",
+			initialConfig: &config.Config{Commands: map[string]map[string]string{}},
+			short:         "build",
+			command:       "generate-release-artifacts build",
+			expectedError: errors.New("invalid prefix 'generate-release-artifacts': 26 characters is over the maximum of 20 (taken from the command; set one with --prefix)"),
+			expectedCmds:  map[string]map[string]string{},
+		},
+		{
+			name:          "Reject a command without a program",
+			initialConfig: &config.Config{Commands: map[string]map[string]string{}},
+			short:         "build",
+			command:       "FOO=1",
+			expectedError: errors.New("could not detect a prefix; set one with --prefix"),
+			expectedCmds:  map[string]map[string]string{},
+		},
+		{
+			name: "Apply the configured maximum length",
+			initialConfig: &config.Config{
+				Commands: map[string]map[string]string{},
+				Settings: config.Settings{MaxNameLength: 5},
+			},
+			prefix:        "git",
+			short:         "push-main",
+			command:       "git push origin main",
+			expectedError: errors.New("invalid short name 'push-main': 9 characters is over the maximum of 5"),
+			expectedCmds:  map[string]map[string]string{},
+		},
 	}
 
 	for _, tt := range tests {
@@ -85,7 +227,11 @@ func TestAddCommand(t *testing.T) {
 			}
 
 			// Run the function under test
-			err = handler.AddCommand(tempFile.Name(), tt.prefix, tt.short, tt.command)
+			opts := handler.AddOptions{Yes: true, Force: tt.force}
+			if tt.input != "" {
+				opts = handler.AddOptions{Input: strings.NewReader(tt.input), Force: tt.force}
+			}
+			err = handler.AddCommand(tempFile.Name(), tt.prefix, tt.short, tt.command, opts)
 
 			// Check for expected error
 			if (err != nil) != (tt.expectedError != nil) {
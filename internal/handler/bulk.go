@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/pkg/ioutil"
+)
+
+type BulkOptions struct {
+	// Yes applies the change without asking for confirmation.
+	Yes bool
+	// Input is read for the confirmation; it defaults to os.Stdin, where
+	// the question is only asked on a terminal.
+	Input io.Reader
+}
+
+// RemoveCommands removes every entry chosen by sel after showing them, and
+// the workflows still running them, and asking for confirmation.
+func RemoveCommands(configPath string, sel domain.Selector, opts BulkOptions) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	refs, err := selectEntries(cfg, sel)
+	if err != nil {
+		return err
+	}
+
+	if err := previewRefs(cfg, fmt.Sprintf("Remove %s:", countEntries(len(refs))), refs, nil); err != nil {
+		return err
+	}
+	if err := previewBrokenWorkflows(cfg, refs); err != nil {
+		return err
+	}
+	if err := confirmBulk(opts); err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		cfg.DeleteEntry(ref.Prefix, ref.Short)
+	}
+
+	if err := saveConfig(cfg, configPath, fmt.Sprintf("remove %s", countEntries(len(refs)))); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	fmt.Printf("Removed %s\n", countEntries(len(refs)))
+	return nil
+}
+
+// MoveCommands moves every entry chosen by sel under toPrefix, keeping
+// their short names, after showing them and asking for confirmation.
+// Workflow steps running a moved entry follow it, and so does its history.
+// Nothing is moved when a short name is already taken under toPrefix.
+func MoveCommands(configPath string, sel domain.Selector, toPrefix string, opts BulkOptions) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if err := namePolicy(cfg).ValidatePrefix(toPrefix); err != nil {
+		return err
+	}
+
+	selected, err := selectEntries(cfg, sel)
+	if err != nil {
+		return err
+	}
+
+	var refs []domain.Ref
+	targets := make(map[string]domain.Ref)
+	var conflicts []string
+	for _, ref := range selected {
+		if ref.Prefix == toPrefix {
+			continue
+		}
+		refs = append(refs, ref)
+
+		if other, ok := targets[ref.Short]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("%s and %s", other, ref))
+		} else if _, ok := cfg.Commands[toPrefix][ref.Short]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("%s %s exists", toPrefix, ref.Short))
+		}
+		targets[ref.Short] = ref
+	}
+	if len(refs) == 0 {
+		return fmt.Errorf("the selected entries are already under %s", toPrefix)
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("short names already taken under %s: %s", toPrefix, strings.Join(conflicts, "; "))
+	}
+
+	if err := previewRefs(cfg, fmt.Sprintf("Move %s to %s:", countEntries(len(refs)), toPrefix), refs, &toPrefix); err != nil {
+		return err
+	}
+	if err := confirmBulk(opts); err != nil {
+		return err
+	}
+
+	moved := make(map[string]string, len(refs))
+	renamed := make(map[domain.Ref]domain.Ref, len(refs))
+	for _, ref := range refs {
+		entry, _ := cfg.GetEntry(ref.Prefix, ref.Short)
+		cfg.DeleteEntry(ref.Prefix, ref.Short)
+		cfg.SetEntry(toPrefix, ref.Short, entry)
+		renamed[ref] = domain.Ref{Prefix: toPrefix, Short: ref.Short}
+		moved[ref.String()] = renamed[ref].String()
+	}
+	retargetSteps(cfg, moved)
+
+	message := fmt.Sprintf("move %s to %s", countEntries(len(refs)), toPrefix)
+	if err := saveConfig(cfg, configPath, message); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+	renameHistory(configPath, renamed)
+
+	fmt.Printf("Moved %s to %s\n", countEntries(len(refs)), toPrefix)
+	return nil
+}
+
+func selectEntries(cfg *config.Config, sel domain.Selector) ([]domain.Ref, error) {
+	commands := make(map[domain.Ref]string)
+	for prefix, cmds := range cfg.Commands {
+		for short, command := range cmds {
+			commands[domain.Ref{Prefix: prefix, Short: short}] = command
+		}
+	}
+
+	refs, err := sel.Select(commands)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("no entries match")
+	}
+	return refs, nil
+}
+
+// previewRefs prints title and the entries about to change. When toPrefix
+// is set, each entry is shown with its new name.
+func previewRefs(cfg *config.Config, title string, refs []domain.Ref, toPrefix *string) error {
+	fmt.Println(title)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, ref := range refs {
+		name := ref.Prefix + " " + ref.Short
+		if toPrefix != nil {
+			name += " -> " + *toPrefix + " " + ref.Short
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", name, ioutil.FirstLine(cfg.Commands[ref.Prefix][ref.Short]))
+	}
+	return w.Flush()
+}
+
+// previewBrokenWorkflows lists the workflows that are kept but run one of
+// the removed refs, whose steps would fail once they are gone.
+func previewBrokenWorkflows(cfg *config.Config, refs []domain.Ref) error {
+	removed := make(map[string]string, len(refs))
+	for _, ref := range refs {
+		removed[ref.String()] = ""
+	}
+
+	var workflows []domain.Ref
+	for _, workflow := range workflowsRunning(cfg, removed) {
+		if _, ok := removed[workflow.String()]; !ok {
+			workflows = append(workflows, workflow)
+		}
+	}
+	if len(workflows) == 0 {
+		return nil
+	}
+	return previewRefs(cfg, "Workflows still running removed commands:", workflows, nil)
+}
+
+// confirmBulk asks whether to apply a previewed change. Without a terminal
+// to ask on, the change needs opts.Yes.
+func confirmBulk(opts BulkOptions) error {
+	if opts.Yes {
+		return nil
+	}
+
+	input, ok := promptInput(opts.Input)
+	if !ok {
+		return fmt.Errorf("confirmation required: run on a terminal or pass --yes")
+	}
+
+	fmt.Fprint(os.Stderr, "Proceed? [y/N]: ")
+	answer, err := readLine(input)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return fmt.Errorf("aborted: nothing was changed")
+}
+
+func countEntries(n int) string {
+	if n == 1 {
+		return "1 entry"
+	}
+	return fmt.Sprintf("%d entries", n)
+}
@@ -0,0 +1,225 @@
+package handler_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+)
+
+const bulkConfig = `
+[commands.docker]
+ps = "docker ps -a"
+images = "docker images"
+
+[commands.container]
+prune = "docker system prune"
+
+[commands.k8s]
+pods-staging = "kubectl get pods --context staging"
+pods-prod = "kubectl get pods --context prod"
+
+[commands.kube]
+pods-prod = "kubectl get pods -n prod"
+
+[commands.release]
+ship = "docker:images -> k8s:pods-prod"
+
+[[meta.release.ship.steps]]
+ref = "docker:images"
+
+[[meta.release.ship.steps]]
+ref = "k8s:pods-prod"
+`
+
+func TestRemoveCommands(t *testing.T) {
+	tests := []struct {
+		name          string
+		selector      domain.Selector
+		opts          handler.BulkOptions
+		expectedError string
+		expectedCmds  map[string]map[string]string
+		// expectedWarn is shown before confirming; "" means no workflow
+		// is listed.
+		expectedWarn string
+	}{
+		{
+			name:     "remove a prefix after confirming",
+			selector: domain.Selector{Prefix: "docker"},
+			opts:     handler.BulkOptions{Input: strings.NewReader("y\n")},
+			expectedCmds: map[string]map[string]string{
+				"container": {"prune": "docker system prune"},
+				"k8s":       {"pods-staging": "kubectl get pods --context staging", "pods-prod": "kubectl get pods --context prod"},
+				"kube":      {"pods-prod": "kubectl get pods -n prod"},
+				"release":   {"ship": "docker:images -> k8s:pods-prod"},
+			},
+			expectedWarn: "Workflows still running removed commands:\n  release ship  docker:images -> k8s:pods-prod\n",
+		},
+		{
+			name:     "remove matching commands with yes",
+			selector: domain.Selector{Match: "kubectl*staging*"},
+			opts:     handler.BulkOptions{Yes: true},
+			expectedCmds: map[string]map[string]string{
+				"docker":    {"ps": "docker ps -a", "images": "docker images"},
+				"container": {"prune": "docker system prune"},
+				"k8s":       {"pods-prod": "kubectl get pods --context prod"},
+				"kube":      {"pods-prod": "kubectl get pods -n prod"},
+				"release":   {"ship": "docker:images -> k8s:pods-prod"},
+			},
+		},
+		{
+			name:     "remove a workflow with the commands it runs",
+			selector: domain.Selector{Match: "docker*"},
+			opts:     handler.BulkOptions{Yes: true},
+			expectedCmds: map[string]map[string]string{
+				"k8s":  {"pods-staging": "kubectl get pods --context staging", "pods-prod": "kubectl get pods --context prod"},
+				"kube": {"pods-prod": "kubectl get pods -n prod"},
+			},
+		},
+		{
+			name:          "keep everything when not confirmed",
+			selector:      domain.Selector{Prefix: "docker"},
+			opts:          handler.BulkOptions{Input: strings.NewReader("n\n")},
+			expectedError: "aborted: nothing was changed",
+			expectedWarn:  "Workflows still running removed commands:\n  release ship  docker:images -> k8s:pods-prod\n",
+		},
+		{
+			name:          "no matching entries",
+			selector:      domain.Selector{Match: "terraform*"},
+			opts:          handler.BulkOptions{Yes: true},
+			expectedError: "no entries match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath, err := createTempConfig(bulkConfig)
+			if err != nil {
+				t.Fatalf("failed to create temp config: %v", err)
+			}
+			defer cleanupTempFile(configPath)
+
+			output := captureStdout(t, func() {
+				err = handler.RemoveCommands(configPath, tt.selector, tt.opts)
+			})
+			if listed := strings.Contains(output, "Workflows"); listed != (tt.expectedWarn != "") || !strings.Contains(output, tt.expectedWarn) {
+				t.Errorf("output = %q, want the workflows listed as %q", output, tt.expectedWarn)
+			}
+			checkBulkResult(t, configPath, err, tt.expectedError, tt.expectedCmds)
+		})
+	}
+}
+
+func TestMoveCommands(t *testing.T) {
+	tests := []struct {
+		name          string
+		selector      domain.Selector
+		toPrefix      string
+		expectedError string
+		expectedCmds  map[string]map[string]string
+		expectedSteps []config.Step
+	}{
+		{
+			name:     "move a prefix and the steps running it",
+			selector: domain.Selector{Prefix: "docker"},
+			toPrefix: "container",
+			expectedCmds: map[string]map[string]string{
+				"container": {"prune": "docker system prune", "ps": "docker ps -a", "images": "docker images"},
+				"k8s":       {"pods-staging": "kubectl get pods --context staging", "pods-prod": "kubectl get pods --context prod"},
+				"kube":      {"pods-prod": "kubectl get pods -n prod"},
+				"release":   {"ship": "container:images -> k8s:pods-prod"},
+			},
+			expectedSteps: []config.Step{{Ref: "container:images"}, {Ref: "k8s:pods-prod"}},
+		},
+		{
+			name:     "move matching commands",
+			selector: domain.Selector{Match: "k8s:*"},
+			toPrefix: "kubectl",
+			expectedCmds: map[string]map[string]string{
+				"docker":    {"ps": "docker ps -a", "images": "docker images"},
+				"container": {"prune": "docker system prune"},
+				"kubectl":   {"pods-staging": "kubectl get pods --context staging", "pods-prod": "kubectl get pods --context prod"},
+				"kube":      {"pods-prod": "kubectl get pods -n prod"},
+				"release":   {"ship": "docker:images -> kubectl:pods-prod"},
+			},
+			expectedSteps: []config.Step{{Ref: "docker:images"}, {Ref: "kubectl:pods-prod"}},
+		},
+		{
+			name:          "conflict with the destination",
+			selector:      domain.Selector{Prefix: "kube"},
+			toPrefix:      "k8s",
+			expectedError: "short names already taken under k8s: k8s pods-prod exists",
+		},
+		{
+			name:          "conflict between the selected entries",
+			selector:      domain.Selector{Match: "*:pods-prod"},
+			toPrefix:      "kubectl",
+			expectedError: "short names already taken under kubectl: k8s:pods-prod and kube:pods-prod",
+		},
+		{
+			name:          "already under the prefix",
+			selector:      domain.Selector{Prefix: "docker"},
+			toPrefix:      "docker",
+			expectedError: "the selected entries are already under docker",
+		},
+		{
+			name:          "invalid destination",
+			selector:      domain.Selector{Prefix: "docker"},
+			toPrefix:      "my tools",
+			expectedError: "invalid prefix 'my tools': a space is not allowed; use letters, digits, '-' or '_'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath, err := createTempConfig(bulkConfig)
+			if err != nil {
+				t.Fatalf("failed to create temp config: %v", err)
+			}
+			defer cleanupTempFile(configPath)
+
+			captureStdout(t, func() {
+				err = handler.MoveCommands(configPath, tt.selector, tt.toPrefix, handler.BulkOptions{Yes: true})
+			})
+			cfg := checkBulkResult(t, configPath, err, tt.expectedError, tt.expectedCmds)
+			if tt.expectedSteps != nil {
+				if steps := cfg.Meta["release"]["ship"].Steps; !reflect.DeepEqual(steps, tt.expectedSteps) {
+					t.Errorf("steps = %v, want %v", steps, tt.expectedSteps)
+				}
+			}
+		})
+	}
+}
+
+// checkBulkResult compares the outcome of a bulk operation on bulkConfig;
+// the book must be unchanged when an error is expected.
+func checkBulkResult(t *testing.T, configPath string, err error, expectedError string, expectedCmds map[string]map[string]string) *config.Config {
+	t.Helper()
+
+	if expectedError != "" {
+		if err == nil || err.Error() != expectedError {
+			t.Fatalf("error = %v, want %s", err, expectedError)
+		}
+		var original config.Config
+		if err := toml.Unmarshal([]byte(bulkConfig), &original); err != nil {
+			t.Fatalf("failed to parse bulkConfig: %v", err)
+		}
+		expectedCmds = original.Commands
+	} else if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if !equalCommands(cfg.Commands, expectedCmds) {
+		t.Errorf("commands = %v, want %v", cfg.Commands, expectedCmds)
+	}
+	return cfg
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+)
+
+const defaultEditor = "vi"
+
+type CopyOptions struct {
+	// Edit opens the copied command in $VISUAL or $EDITOR before it is
+	// saved.
+	Edit bool
+	// Force replaces an existing entry at the target prefix and short.
+	Force bool
+}
+
+// CopyCommand duplicates an entry with its settings under toPrefix and
+// toShort. An empty toPrefix keeps the prefix; an empty toShort keeps the
+// short name, numbered when it is taken under the new prefix. A given
+// toShort that is taken is only replaced with opts.Force.
+func CopyCommand(configPath, prefix, short, toPrefix, toShort string, opts CopyOptions) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	entry, ok := cfg.GetEntry(prefix, short)
+	if !ok {
+		return fmt.Errorf("command not found: %s %s", prefix, short)
+	}
+	if opts.Edit && entry.IsWorkflow() {
+		return fmt.Errorf("%s %s is a workflow; change its steps with cb update --step", prefix, short)
+	}
+
+	policy := namePolicy(cfg)
+	if toPrefix == "" {
+		toPrefix = prefix
+	} else if err := policy.ValidatePrefix(toPrefix); err != nil {
+		return err
+	}
+
+	taken := func(s string) bool {
+		_, exists := cfg.Commands[toPrefix][s]
+		return exists
+	}
+	if toShort == "" {
+		toShort = domain.UniqueShort(short, policy, taken)
+	} else if err := policy.ValidateShort(toShort); err != nil {
+		return err
+	} else if toPrefix == prefix && toShort == short {
+		return fmt.Errorf("cannot copy %s %s onto itself", prefix, short)
+	} else if taken(toShort) && !opts.Force {
+		return fmt.Errorf("short already exists: %s %s (use --force to replace it)", toPrefix, toShort)
+	}
+
+	entry.Meta = entry.Meta.Clone()
+	if opts.Edit {
+		if entry.Command, err = editCommand(entry.Command); err != nil {
+			return err
+		}
+	}
+
+	cfg.SetEntry(toPrefix, toShort, entry)
+	message := fmt.Sprintf("copy %s %s -> %s %s", prefix, short, toPrefix, toShort)
+	if err := saveConfig(cfg, configPath, message); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	fmt.Printf("Copied: %s %s -> %s %s\n", prefix, short, toPrefix, toShort)
+	return nil
+}
+
+// editCommand opens command in the user's editor and returns the edited
+// text. The editor may be given with arguments, such as "code --wait".
+func editCommand(command string) (string, error) {
+	file, err := os.CreateTemp("", "cmdbook-*.sh")
+	if err != nil {
+		return "", fmt.Errorf("failed to create a file to edit: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(command + "\n"); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write %s: %w", file.Name(), err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", file.Name(), err)
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = defaultEditor
+	}
+
+	editCmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", file.Name())
+	editCmd.Stdin = os.Stdin
+	editCmd.Stdout = os.Stdout
+	editCmd.Stderr = os.Stderr
+	if err := editCmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read the edited command: %w", err)
+	}
+	edited := strings.TrimRight(string(data), "\n")
+	if strings.TrimSpace(edited) == "" {
+		return "", fmt.Errorf("the edited command is empty; nothing was copied")
+	}
+	return edited, nil
+}
@@ -0,0 +1,182 @@
+package handler_test
+
+import (
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+)
+
+const copyConfig = `
+[commands.aws]
+ls = "aws s3 ls"
+ls-2 = "aws s3 ls --recursive"
+
+[commands.release]
+ship = "aws:ls"
+
+[meta.aws.ls]
+dir = "~/infra"
+env = { AWS_PROFILE = "prod" }
+
+[[meta.release.ship.steps]]
+ref = "aws:ls"
+`
+
+func TestCopyCommand(t *testing.T) {
+	tests := []struct {
+		name          string
+		prefix        string
+		short         string
+		toPrefix      string
+		toShort       string
+		edit          bool
+		force         bool
+		editor        string
+		expectedError string
+		expectedRef   [2]string
+		expected      config.Entry
+	}{
+		{
+			name:        "copy under a new short with settings",
+			prefix:      "aws",
+			short:       "ls",
+			toShort:     "ls-dev",
+			expectedRef: [2]string{"aws", "ls-dev"},
+			expected: config.Entry{
+				Command: "aws s3 ls",
+				Meta:    config.EntryMeta{Dir: "~/infra", Env: map[string]string{"AWS_PROFILE": "prod"}},
+			},
+		},
+		{
+			name:        "copy to another prefix keeping the short",
+			prefix:      "aws",
+			short:       "ls",
+			toPrefix:    "s3",
+			expectedRef: [2]string{"s3", "ls"},
+			expected: config.Entry{
+				Command: "aws s3 ls",
+				Meta:    config.EntryMeta{Dir: "~/infra", Env: map[string]string{"AWS_PROFILE": "prod"}},
+			},
+		},
+		{
+			name:        "copy numbered when the short is taken",
+			prefix:      "aws",
+			short:       "ls",
+			expectedRef: [2]string{"aws", "ls-3"},
+			expected: config.Entry{
+				Command: "aws s3 ls",
+				Meta:    config.EntryMeta{Dir: "~/infra", Env: map[string]string{"AWS_PROFILE": "prod"}},
+			},
+		},
+		{
+			name:        "copy a workflow",
+			prefix:      "release",
+			short:       "ship",
+			toShort:     "ship-again",
+			expectedRef: [2]string{"release", "ship-again"},
+			expected:    config.Entry{Command: "aws:ls", Meta: config.EntryMeta{Steps: []config.Step{{Ref: "aws:ls"}}}},
+		},
+		{
+			name:        "edit the copy",
+			prefix:      "aws",
+			short:       "ls-2",
+			toShort:     "du",
+			edit:        true,
+			editor:      "sed -i 's/ls/ls --summarize/'",
+			expectedRef: [2]string{"aws", "du"},
+			expected:    config.Entry{Command: "aws s3 ls --summarize --recursive"},
+		},
+		{
+			name:          "nothing copied when the edit is empty",
+			prefix:        "aws",
+			short:         "ls",
+			toShort:       "empty",
+			edit:          true,
+			editor:        "truncate -s 0",
+			expectedError: "the edited command is empty; nothing was copied",
+		},
+		{
+			name:          "existing short",
+			prefix:        "aws",
+			short:         "ls",
+			toShort:       "ls-2",
+			expectedError: "short already exists: aws ls-2 (use --force to replace it)",
+		},
+		{
+			name:        "existing short replaced with force",
+			prefix:      "aws",
+			short:       "ls",
+			toShort:     "ls-2",
+			force:       true,
+			expectedRef: [2]string{"aws", "ls-2"},
+			expected: config.Entry{
+				Command: "aws s3 ls",
+				Meta:    config.EntryMeta{Dir: "~/infra", Env: map[string]string{"AWS_PROFILE": "prod"}},
+			},
+		},
+		{
+			name:          "copy onto itself",
+			prefix:        "aws",
+			short:         "ls",
+			toShort:       "ls",
+			force:         true,
+			expectedError: "cannot copy aws ls onto itself",
+		},
+		{
+			name:          "invalid short",
+			prefix:        "aws",
+			short:         "ls",
+			toShort:       "ls dev",
+			expectedError: "invalid short name 'ls dev': a space is not allowed; use letters, digits, '-' or '_'",
+		},
+		{
+			name:          "missing entry",
+			prefix:        "aws",
+			short:         "cp",
+			expectedError: "command not found: aws cp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath, err := createTempConfig(copyConfig)
+			if err != nil {
+				t.Fatalf("failed to create temp config: %v", err)
+			}
+			defer cleanupTempFile(configPath)
+			t.Setenv("VISUAL", "")
+			t.Setenv("EDITOR", tt.editor)
+
+			captureStdout(t, func() {
+				err = handler.CopyCommand(configPath, tt.prefix, tt.short, tt.toPrefix, tt.toShort, handler.CopyOptions{Edit: tt.edit, Force: tt.force})
+			})
+
+			if tt.expectedError != "" {
+				if err == nil || err.Error() != tt.expectedError {
+					t.Fatalf("CopyCommand() error = %v, want %s", err, tt.expectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CopyCommand() error = %v", err)
+			}
+
+			cfg, err := config.LoadConfig(configPath)
+			if err != nil {
+				t.Fatalf("failed to load config: %v", err)
+			}
+			entry, ok := cfg.GetEntry(tt.expectedRef[0], tt.expectedRef[1])
+			if !ok {
+				t.Fatalf("copy %s %s not found in %v", tt.expectedRef[0], tt.expectedRef[1], cfg.Commands)
+			}
+			if !entry.Equal(tt.expected) {
+				t.Errorf("copy = %+v, want %+v", entry, tt.expected)
+			}
+
+			if original, _ := cfg.GetEntry(tt.prefix, tt.short); original.Equal(entry) && tt.edit {
+				t.Errorf("original entry was edited: %+v", original)
+			}
+		})
+	}
+}
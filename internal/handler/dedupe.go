@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/pkg/ioutil"
+)
+
+type DedupeOptions struct {
+	// Input is read for the entries to keep of each group; it defaults to
+	// os.Stdin, where duplicates are only merged on a terminal.
+	Input io.Reader
+}
+
+// Dedupe lists the commands of the book that are saved more than once,
+// exactly or nearly, and removes the entries of each group the user does
+// not keep. Workflow steps running a removed exact duplicate are pointed at
+// the kept entry; removing a near duplicate that a workflow runs is only
+// warned about, since the kept command differs from it.
+func Dedupe(configPath string, opts DedupeOptions) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	commands := make(map[domain.Ref]string)
+	for prefix, cmds := range cfg.Commands {
+		for short, command := range cmds {
+			if entry, _ := cfg.GetEntry(prefix, short); !entry.IsWorkflow() {
+				commands[domain.Ref{Prefix: prefix, Short: short}] = command
+			}
+		}
+	}
+
+	groups := domain.FindDuplicates(commands, func(a, b domain.Ref) bool {
+		x, _ := cfg.GetEntry(a.Prefix, a.Short)
+		y, _ := cfg.GetEntry(b.Prefix, b.Short)
+		return x.Meta.Equal(y.Meta)
+	})
+	if len(groups) == 0 {
+		fmt.Println("No duplicates found")
+		return nil
+	}
+
+	input, interactive := promptInput(opts.Input)
+	removed := 0
+	for i, group := range groups {
+		kind := "Similar commands"
+		if group.Exact {
+			kind = "Same command"
+		}
+		fmt.Printf("%s (%d/%d):\n", kind, i+1, len(groups))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for j, ref := range group.Refs {
+			fmt.Fprintf(w, "  %d) %s %s\t%s\n", j+1, ref.Prefix, ref.Short, ioutil.FirstLine(commands[ref]))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if !interactive {
+			continue
+		}
+
+		kept, quit, err := chooseKept(group.Refs, input)
+		if err != nil {
+			return err
+		}
+		if quit {
+			break
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		removed += mergeDuplicates(cfg, group, kept)
+	}
+
+	if !interactive {
+		fmt.Println("Run cb dedupe on a terminal to merge them")
+		return nil
+	}
+	if removed == 0 {
+		fmt.Println("Nothing was removed")
+		return nil
+	}
+
+	if err := saveConfig(cfg, configPath, fmt.Sprintf("dedupe %d entries", removed)); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+	fmt.Printf("Removed %d duplicate entries\n", removed)
+	return nil
+}
+
+// chooseKept asks which of refs to keep, as one or more numbers separated
+// by commas or spaces. kept is empty when the group is skipped, and quit is
+// set when no further groups should be merged.
+func chooseKept(refs []domain.Ref, input io.Reader) (kept []domain.Ref, quit bool, err error) {
+	fmt.Fprintf(os.Stderr, "Keep [1-%d, several as 1,3], press Enter to skip, or q to stop: ", len(refs))
+
+	answer, err := readLine(input)
+	if err != nil && err != io.EOF {
+		return nil, false, fmt.Errorf("failed to read selection: %w", err)
+	}
+	switch answer = strings.TrimSpace(answer); answer {
+	case "":
+		return nil, false, nil
+	case "q":
+		return nil, true, nil
+	}
+
+	for _, field := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ' ' }) {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > len(refs) {
+			return nil, false, fmt.Errorf("invalid selection: %s", answer)
+		}
+		if !slices.Contains(kept, refs[n-1]) {
+			kept = append(kept, refs[n-1])
+		}
+	}
+	return kept, false, nil
+}
+
+// mergeDuplicates removes the refs of group that are not kept and returns
+// how many were removed.
+func mergeDuplicates(cfg *config.Config, group domain.DuplicateGroup, kept []domain.Ref) int {
+	replaced := make(map[string]string)
+	for _, ref := range group.Refs {
+		if slices.Contains(kept, ref) {
+			continue
+		}
+		cfg.DeleteEntry(ref.Prefix, ref.Short)
+		replaced[ref.String()] = kept[0].String()
+	}
+
+	if group.Exact {
+		retargetSteps(cfg, replaced)
+		return len(replaced)
+	}
+	for _, workflow := range workflowsRunning(cfg, replaced) {
+		fmt.Fprintf(os.Stderr, "Warning: workflow %s %s runs a removed command\n", workflow.Prefix, workflow.Short)
+	}
+	return len(replaced)
+}
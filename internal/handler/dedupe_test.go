@@ -0,0 +1,169 @@
+package handler_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+)
+
+const dedupeConfig = `
+[commands.git]
+pm = "git push origin main"
+push-main = "git  push origin main"
+pull = "git pull --rebase"
+
+[commands.k8s]
+pods = "kubectl get pods -n prod"
+
+[commands.kubectl]
+gp = "kubectl get pod -n prod"
+
+[commands.release]
+ship = "git:push-main -> k8s:pods"
+
+[[meta.release.ship.steps]]
+ref = "git:push-main"
+
+[[meta.release.ship.steps]]
+ref = "k8s:pods"
+`
+
+func TestDedupe(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedError string
+		expectedCmds  map[string]map[string]string
+		expectedSteps []config.Step
+	}{
+		{
+			name:  "merge into the kept entries",
+			input: "1\n2\n",
+			expectedCmds: map[string]map[string]string{
+				"git":     {"pm": "git push origin main", "pull": "git pull --rebase"},
+				"kubectl": {"gp": "kubectl get pod -n prod"},
+				"release": {"ship": "git:pm -> k8s:pods"},
+			},
+			// Only exact duplicates are replaced in workflows.
+			expectedSteps: []config.Step{{Ref: "git:pm"}, {Ref: "k8s:pods"}},
+		},
+		{
+			name:  "keep several entries of a group",
+			input: "2\n1, 2\n",
+			expectedCmds: map[string]map[string]string{
+				"git":     {"push-main": "git  push origin main", "pull": "git pull --rebase"},
+				"k8s":     {"pods": "kubectl get pods -n prod"},
+				"kubectl": {"gp": "kubectl get pod -n prod"},
+				"release": {"ship": "git:push-main -> k8s:pods"},
+			},
+			expectedSteps: []config.Step{{Ref: "git:push-main"}, {Ref: "k8s:pods"}},
+		},
+		{
+			name:  "skip a group and stop",
+			input: "\nq\n",
+			expectedCmds: map[string]map[string]string{
+				"git":     {"pm": "git push origin main", "push-main": "git  push origin main", "pull": "git pull --rebase"},
+				"k8s":     {"pods": "kubectl get pods -n prod"},
+				"kubectl": {"gp": "kubectl get pod -n prod"},
+				"release": {"ship": "git:push-main -> k8s:pods"},
+			},
+			expectedSteps: []config.Step{{Ref: "git:push-main"}, {Ref: "k8s:pods"}},
+		},
+		{
+			name:          "reject an invalid selection",
+			input:         "3\n",
+			expectedError: "invalid selection: 3",
+			expectedCmds: map[string]map[string]string{
+				"git":     {"pm": "git push origin main", "push-main": "git  push origin main", "pull": "git pull --rebase"},
+				"k8s":     {"pods": "kubectl get pods -n prod"},
+				"kubectl": {"gp": "kubectl get pod -n prod"},
+				"release": {"ship": "git:push-main -> k8s:pods"},
+			},
+			expectedSteps: []config.Step{{Ref: "git:push-main"}, {Ref: "k8s:pods"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath, err := createTempConfig(dedupeConfig)
+			if err != nil {
+				t.Fatalf("failed to create temp config: %v", err)
+			}
+			defer cleanupTempFile(configPath)
+
+			output := captureStdout(t, func() {
+				err = handler.Dedupe(configPath, handler.DedupeOptions{Input: strings.NewReader(tt.input)})
+			})
+			if tt.expectedError != "" {
+				if err == nil || err.Error() != tt.expectedError {
+					t.Fatalf("Dedupe() error = %v, want %s", err, tt.expectedError)
+				}
+			} else if err != nil {
+				t.Fatalf("Dedupe() error = %v", err)
+			}
+			if !strings.Contains(output, "Same command (1/2):") {
+				t.Errorf("output does not list the exact duplicates:\n%s", output)
+			}
+
+			cfg, err := config.LoadConfig(configPath)
+			if err != nil {
+				t.Fatalf("failed to load config: %v", err)
+			}
+			if !equalCommands(cfg.Commands, tt.expectedCmds) {
+				t.Errorf("commands = %v, want %v", cfg.Commands, tt.expectedCmds)
+			}
+			if steps := cfg.Meta["release"]["ship"].Steps; !reflect.DeepEqual(steps, tt.expectedSteps) {
+				t.Errorf("steps = %v, want %v", steps, tt.expectedSteps)
+			}
+		})
+	}
+}
+
+func TestDedupeComparesSettings(t *testing.T) {
+	configPath, err := createTempConfig(`
+[commands.tf]
+apply-dev = "terraform apply"
+apply-prod = "terraform apply"
+
+[meta.tf.apply-dev]
+env = { AWS_PROFILE = "dev" }
+
+[meta.tf.apply-prod]
+env = { AWS_PROFILE = "prod" }
+`)
+	if err != nil {
+		t.Fatalf("failed to create temp config: %v", err)
+	}
+	defer cleanupTempFile(configPath)
+
+	output := captureStdout(t, func() {
+		err = handler.Dedupe(configPath, handler.DedupeOptions{Input: strings.NewReader("\n")})
+	})
+	if err != nil {
+		t.Fatalf("Dedupe() error = %v", err)
+	}
+	if !strings.Contains(output, "Similar commands (1/1):") {
+		t.Errorf("entries with different settings must not be listed as the same command:\n%s", output)
+	}
+}
+
+func TestDedupeWithoutDuplicates(t *testing.T) {
+	configPath, err := createTempConfig("[commands.git]\npm = \"git push origin main\"\n")
+	if err != nil {
+		t.Fatalf("failed to create temp config: %v", err)
+	}
+	defer cleanupTempFile(configPath)
+
+	output := captureStdout(t, func() {
+		err = handler.Dedupe(configPath, handler.DedupeOptions{Input: strings.NewReader("")})
+	})
+	if err != nil {
+		t.Fatalf("Dedupe() error = %v", err)
+	}
+	if output != "No duplicates found\n" {
+		t.Errorf("output = %q", output)
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+)
+
+// DiffBooks compares the book at fromPath with the book at toPath. With an
+// empty toPath, the current book is compared with the book at fromPath
+// instead; a current book that does not exist yet is empty.
+func DiffBooks(configPath, fromPath, toPath string, asJSON bool) error {
+	var from *config.Config
+	var err error
+	if toPath == "" {
+		toPath = fromPath
+		from, err = config.LoadConfig(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+	} else if from, err = loadBook(fromPath); err != nil {
+		return err
+	}
+
+	to, err := loadBook(toPath)
+	if err != nil {
+		return err
+	}
+
+	changes := config.Diff(from, to)
+
+	if asJSON {
+		if changes == nil {
+			changes = []config.Change{}
+		}
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode diff: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("No differences")
+		return nil
+	}
+
+	for _, c := range changes {
+		fmt.Println(formatChange(c))
+	}
+	return nil
+}
+
+func formatChange(c config.Change) string {
+	switch c.Kind {
+	case config.ChangeAdded:
+		return fmt.Sprintf("+ %s %s: %s", c.Prefix, c.Short, c.Command)
+	case config.ChangeRemoved:
+		return fmt.Sprintf("- %s %s: %s", c.Prefix, c.Short, c.Command)
+	case config.ChangeRenamed:
+		return fmt.Sprintf("> %s %s -> %s %s: %s", c.OldPrefix, c.OldShort, c.Prefix, c.Short, c.Command)
+	default:
+		if c.Command == c.OldCommand {
+			return fmt.Sprintf("~ %s %s: settings changed", c.Prefix, c.Short)
+		}
+		return fmt.Sprintf("~ %s %s: %s -> %s", c.Prefix, c.Short, c.OldCommand, c.Command)
+	}
+}
+
+// loadBook loads a book given on the command line. Unlike
+// config.LoadConfig, a missing file is an error, as it is most likely a
+// mistyped path.
+func loadBook(path string) (*config.Config, error) {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("book not found: %s", path)
+		}
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	return cfg, nil
+}
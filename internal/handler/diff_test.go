@@ -0,0 +1,136 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+)
+
+// Helper function to capture what fn writes to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestDiffBooks(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.toml")
+	otherPath := filepath.Join(dir, "other.toml")
+
+	if err := os.WriteFile(configPath, []byte(`
+[commands.git]
+st = "git status"
+cmd0 = "git push origin main"
+`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if err := os.WriteFile(otherPath, []byte(`
+[commands.git]
+st = "git status -s"
+push-main = "git push origin main"
+`), 0644); err != nil {
+		t.Fatalf("failed to write other book: %v", err)
+	}
+
+	t.Run("human output against the current book", func(t *testing.T) {
+		var err error
+		out := captureStdout(t, func() {
+			err = handler.DiffBooks(configPath, otherPath, "", false)
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, want := range []string{
+			"~ git st: git status -> git status -s",
+			"> git cmd0 -> git push-main: git push origin main",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("output missing %q:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("json output", func(t *testing.T) {
+		var err error
+		out := captureStdout(t, func() {
+			err = handler.DiffBooks(configPath, configPath, otherPath, true)
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var changes []config.Change
+		if err := json.Unmarshal([]byte(out), &changes); err != nil {
+			t.Fatalf("invalid json %q: %v", out, err)
+		}
+		if len(changes) != 2 {
+			t.Errorf("expected 2 changes, got %+v", changes)
+		}
+	})
+
+	t.Run("no differences", func(t *testing.T) {
+		out := captureStdout(t, func() {
+			_ = handler.DiffBooks(configPath, configPath, configPath, false)
+		})
+		if strings.TrimSpace(out) != "No differences" {
+			t.Errorf("unexpected output %q", out)
+		}
+	})
+
+	t.Run("missing books are reported", func(t *testing.T) {
+		missing := filepath.Join(dir, "missing.toml")
+		for _, args := range [][2]string{{missing, ""}, {missing, configPath}, {configPath, missing}} {
+			var err error
+			captureStdout(t, func() {
+				err = handler.DiffBooks(configPath, args[0], args[1], true)
+			})
+			if err == nil || err.Error() != "book not found: "+missing {
+				t.Errorf("DiffBooks(%q, %q) error = %v", args[0], args[1], err)
+			}
+		}
+	})
+
+	t.Run("a missing current book is empty", func(t *testing.T) {
+		var err error
+		out := captureStdout(t, func() {
+			err = handler.DiffBooks(filepath.Join(dir, "new.toml"), otherPath, "", false)
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, want := range []string{
+			"+ git st: git status -s",
+			"+ git push-main: git push origin main",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("output missing %q:\n%s", want, out)
+			}
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseEnv converts KEY=VALUE assignments into a map.
+func parseEnv(assignments []string) (map[string]string, error) {
+	if len(assignments) == 0 {
+		return nil, nil
+	}
+
+	env := make(map[string]string, len(assignments))
+	for _, assignment := range assignments {
+		key, value, ok := strings.Cut(assignment, "=")
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("invalid environment variable '%s': use KEY=VALUE", assignment)
+		}
+		env[key] = value
+	}
+	return env, nil
+}
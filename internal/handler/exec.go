@@ -1,32 +1,285 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
 
 	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/history"
+	"github.com/pHo9UBenaA/cmdbook/internal/risk"
 )
 
-func ExecCommand(configPath, prefix, short string) error {
+type ExecOptions struct {
+	// Yes skips the confirmation of high-risk commands.
+	Yes bool
+	// Here runs the command in the current directory instead of the stored one.
+	Here bool
+	// Input is read for confirmations and for picking among suggestions;
+	// it defaults to os.Stdin, where suggestions are only offered on a
+	// terminal.
+	Input io.Reader
+	// Timeout, Retry, RetryDelay and RetryOn override the entry's settings
+	// when set.
+	Timeout    time.Duration
+	Retry      int
+	RetryDelay time.Duration
+	RetryOn    []string
+	// From resumes a workflow at the given step, a number or prefix:short.
+	From string
+	// Capture stores the output of the run for cb output.
+	Capture bool
+	// Args are passed on to the command, as given after -- on the command
+	// line.
+	Args []string
+	// Set gives values to the {{name}} template variables of the commands
+	// as NAME=VALUE assignments.
+	Set []string
+}
+
+// ExecCommand runs the entry named by prefix and short. An empty prefix
+// finds short under whichever prefix holds it.
+func ExecCommand(configPath, prefix, short string, opts ExecOptions) error {
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
 		return err
 	}
 
-	cmds, exists := cfg.Commands[prefix]
-	if !exists {
-		return fmt.Errorf("command not found: %s %s", prefix, short)
+	ref, err := resolveRef(configPath, cfg, prefix, short, opts)
+	if err != nil {
+		return err
+	}
+
+	job, err := prepareJob(configPath, cfg, ref, opts)
+	if err != nil {
+		return err
+	}
+
+	err = job.run(context.Background(), os.Stdin, os.Stdout, os.Stderr, opts)
+
+	var stepErr *stepError
+	if errors.As(err, &stepErr) {
+		fmt.Fprintf(os.Stderr, "Resume with: cb exec %s %s --from %d\n", ref.Prefix, ref.Short, stepErr.step)
+	}
+	return err
+}
+
+// execJob is a looked up and confirmed entry, ready to be run.
+type execJob struct {
+	configPath string
+	ref        domain.Ref
+	entry      config.Entry
+	shell      string
+	policy     runPolicy
+	// vars holds the values given to the template variables of the
+	// command, which is already rendered with them.
+	vars map[string]string
+	// workflow is set when entry is a workflow.
+	workflow *workflowRun
+}
+
+func prepareJob(configPath string, cfg *config.Config, ref domain.Ref, opts ExecOptions) (execJob, error) {
+	entry, err := lookupCommand(configPath, cfg, ref.Prefix, ref.Short)
+	if err != nil {
+		return execJob{}, err
+	}
+
+	if entry.IsWorkflow() {
+		return prepareWorkflow(configPath, cfg, ref, entry, opts)
+	}
+	if opts.From != "" {
+		return execJob{}, fmt.Errorf("--from only applies to workflows: %s %s is a command", ref.Prefix, ref.Short)
+	}
+
+	var vars map[string]string
+	if entry.Command, vars, err = renderCommand(ref, entry.Command, opts); err != nil {
+		return execJob{}, err
+	}
+
+	if err := confirmRisk(cfg, ref.Prefix, ref.Short, withArgs(entry.Command, opts.Args), opts); err != nil {
+		return execJob{}, err
+	}
+
+	policy, err := resolvePolicy(entry.Meta, opts)
+	if err != nil {
+		return execJob{}, err
 	}
 
-	command, ok := cmds[short]
-	if !ok {
-		return fmt.Errorf("command not found: %s %s", prefix, short)
+	return execJob{configPath: configPath, ref: ref, entry: entry, shell: entryShell(cfg, entry), policy: policy, vars: vars}, nil
+}
+
+// run executes the job and appends the outcome to the history.
+func (j execJob) run(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, opts ExecOptions) error {
+	rec := newRecord(j, opts.Args)
+	warnings := stderr
+
+	var capture *history.Capture
+	if opts.Capture {
+		var err error
+		if capture, err = history.NewCapture(j.configPath, rec.ID); err != nil {
+			fmt.Fprintln(warnings, "Warning: failed to capture output:", err)
+		} else {
+			stdout, stderr = capture.Tee(stdout), capture.Tee(stderr)
+			rec.Output = true
+			// The steps of a workflow are captured as part of it.
+			opts.Capture = false
+		}
 	}
 
-	execCmd := exec.Command("sh", "-c", command)
-	execCmd.Stdout = os.Stdout
-	execCmd.Stderr = os.Stderr
-	execCmd.Stdin = os.Stdin
-	return execCmd.Run()
+	var err error
+	if j.workflow != nil {
+		err = j.runWorkflow(ctx, stdin, stdout, stderr, opts)
+	} else {
+		err = supervise(ctx, j.policy, stderr, func(ctx context.Context) (*exec.Cmd, func(), error) {
+			execCmd, cleanup, err := buildExecCmd(ctx, j.entry, j.shell, opts)
+			if err != nil {
+				return nil, nil, err
+			}
+			if execCmd.Dir != "" {
+				rec.Dir = execCmd.Dir
+			}
+			execCmd.Stdin = stdin
+			execCmd.Stdout = stdout
+			execCmd.Stderr = stderr
+			return execCmd, cleanup, nil
+		})
+	}
+
+	if capture != nil {
+		finishCapture(j.configPath, capture, warnings)
+	}
+	recordRun(j.configPath, rec, err, warnings)
+	return err
+}
+
+// buildExecCmd prepares the process running entry with shell, applying its
+// stored working directory and environment. Environment values are passed
+// as stored, so a '$' in them stays literal. The returned cleanup must be
+// called once the process has finished.
+func buildExecCmd(ctx context.Context, entry config.Entry, shell string, opts ExecOptions) (*exec.Cmd, func(), error) {
+	var dir string
+	if entry.Meta.Dir != "" && !opts.Here {
+		expanded, err := expandPath(entry.Meta.Dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		if info, err := os.Stat(expanded); err != nil || !info.IsDir() {
+			return nil, nil, fmt.Errorf("working directory not found: %s", expanded)
+		}
+		dir = expanded
+	}
+
+	execCmd, cleanup, err := shellCommand(ctx, shell, entry.Command, opts.Args)
+	if err != nil {
+		return nil, nil, err
+	}
+	execCmd.Dir = dir
+
+	if len(entry.Meta.Env) > 0 {
+		execCmd.Env = os.Environ()
+		keys := make([]string, 0, len(entry.Meta.Env))
+		for key := range entry.Meta.Env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			execCmd.Env = append(execCmd.Env, key+"="+entry.Meta.Env[key])
+		}
+	}
+
+	return execCmd, cleanup, nil
+}
+
+// expandPath expands a leading ~ and environment variables in path.
+func expandPath(path string) (string, error) {
+	path = os.ExpandEnv(path)
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to expand %s: %w", path, err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+// withArgs returns command as it is classified when run with args, which
+// may make it riskier, as in "git push" with "--force".
+func withArgs(command string, args []string) string {
+	if len(args) == 0 {
+		return command
+	}
+	return command + " " + strings.Join(args, " ")
+}
+
+// confirmRisk asks the user to type the short name of a high-risk command
+// before it is run.
+func confirmRisk(cfg *config.Config, prefix, short, command string, opts ExecOptions) error {
+	assessment, err := risk.Classify(command, cfg.RiskRules)
+	if err != nil {
+		return err
+	}
+	if assessment.Level < risk.High || opts.Yes {
+		return nil
+	}
+
+	input := opts.Input
+	if input == nil {
+		input = os.Stdin
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: %s %s is high risk (%s)\n  %s\n", prefix, short, assessment.Reason, command)
+	fmt.Fprintf(os.Stderr, "Type '%s' to run it: ", short)
+
+	answer, err := readLine(input)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	if strings.TrimSpace(answer) != short {
+		return fmt.Errorf("aborted: %s %s was not confirmed", prefix, short)
+	}
+	return nil
+}
+
+// readLine reads a single line without buffering past it, so the rest of
+// the input is left for the command being run.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				return string(line), nil
+			}
+			line = append(line, buf[0])
+		}
+		if err != nil {
+			return string(line), err
+		}
+	}
+}
+
+// promptInput returns where to read the answer to a question from: input
+// when given, otherwise stdin when both it and stderr are terminals. ok is
+// false when there is no one to ask.
+func promptInput(input io.Reader) (r io.Reader, ok bool) {
+	if input != nil {
+		return input, true
+	}
+	if term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stderr.Fd())) {
+		return os.Stdin, true
+	}
+	return nil, false
 }
@@ -2,30 +2,34 @@ package handler_test
 
 import (
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pHo9UBenaA/cmdbook/internal/handler"
 )
 
-// Helper function to create a temporary configuration file
+// Helper function to create a temporary configuration file in its own
+// directory, which also receives the data directory of the book
 func createTempConfig(content string) (string, error) {
-	tmpFile, err := os.CreateTemp("", "testconfig_*.toml")
+	dir, err := os.MkdirTemp("", "testconfig_*")
 	if err != nil {
 		return "", err
 	}
-	defer tmpFile.Close()
 
-	_, err = tmpFile.WriteString(content)
-	if err != nil {
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
 		return "", err
 	}
 
-	return tmpFile.Name(), nil
+	return path, nil
 }
 
 // Helper function to clean up temporary files
 func cleanupTempFile(path string) {
-	_ = os.Remove(path)
+	_ = os.RemoveAll(filepath.Dir(path))
 }
 
 func TestExecCommand(t *testing.T) {
@@ -104,7 +108,7 @@ fail = "exit 1"
 			}
 
 			// Execute the function
-			err = handler.ExecCommand(configPath, tt.prefix, tt.short)
+			err = handler.ExecCommand(configPath, tt.prefix, tt.short, handler.ExecOptions{})
 
 			// Validate the error
 			if (err != nil && err.Error() != tt.expectedError) || (err == nil && tt.expectedError != "") {
@@ -113,3 +117,433 @@ fail = "exit 1"
 		})
 	}
 }
+
+func TestExecCommandRiskConfirmation(t *testing.T) {
+	configContent := `
+[commands.db]
+reset = "echo resetting database"
+push = "echo git push"
+
+[[risk_rules]]
+pattern = "resetting database"
+level = "high"
+`
+
+	tests := []struct {
+		name          string
+		short         string
+		opts          handler.ExecOptions
+		expectedError string
+	}{
+		{
+			name: "confirmed by typing the short name",
+			opts: handler.ExecOptions{Input: strings.NewReader("reset\n")},
+		},
+		{
+			name:          "wrong confirmation aborts",
+			opts:          handler.ExecOptions{Input: strings.NewReader("yes\n")},
+			expectedError: "aborted: db reset was not confirmed",
+		},
+		{
+			name:          "no input aborts",
+			opts:          handler.ExecOptions{Input: strings.NewReader("")},
+			expectedError: "aborted: db reset was not confirmed",
+		},
+		{
+			name: "--yes skips the confirmation",
+			opts: handler.ExecOptions{Yes: true, Input: strings.NewReader("")},
+		},
+		{
+			name:          "arguments are classified with the command",
+			short:         "push",
+			opts:          handler.ExecOptions{Args: []string{"--force"}, Input: strings.NewReader("")},
+			expectedError: "aborted: db push was not confirmed",
+		},
+		{
+			name:  "harmless arguments need no confirmation",
+			short: "push",
+			opts:  handler.ExecOptions{Args: []string{"--dry-run"}, Input: strings.NewReader("")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath, err := createTempConfig(configContent)
+			if err != nil {
+				t.Fatalf("failed to create temp config file: %v", err)
+			}
+			defer cleanupTempFile(configPath)
+
+			short := tt.short
+			if short == "" {
+				short = "reset"
+			}
+			err = handler.ExecCommand(configPath, "db", short, tt.opts)
+			if (err != nil && err.Error() != tt.expectedError) || (err == nil && tt.expectedError != "") {
+				t.Errorf("unexpected error: got %v, want %v", err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestExecCommandDirAndEnv(t *testing.T) {
+	workDir := t.TempDir()
+	t.Setenv("CMDBOOK_TEST_REGION", "eu")
+
+	configContent := `
+[commands.aws]
+ls = 'test "$(pwd -P)" = "$(cd ` + workDir + ` && pwd -P)" && test "$AWS_PROFILE" = "prod-\$CMDBOOK_TEST_REGION"'
+here = 'test "$(pwd -P)" != "$(cd ` + workDir + ` && pwd -P)"'
+gone = "true"
+
+[meta.aws.ls]
+dir = "` + workDir + `"
+env = { AWS_PROFILE = "prod-$CMDBOOK_TEST_REGION" }
+
+[meta.aws.here]
+dir = "` + workDir + `"
+
+[meta.aws.gone]
+dir = "` + workDir + `/missing"
+`
+
+	tests := []struct {
+		name          string
+		short         string
+		opts          handler.ExecOptions
+		expectedError string
+	}{
+		{
+			name:  "runs in the stored directory with the stored environment as is",
+			short: "ls",
+		},
+		{
+			name:  "--here ignores the stored directory",
+			short: "here",
+			opts:  handler.ExecOptions{Here: true},
+		},
+		{
+			name:          "missing directory is reported",
+			short:         "gone",
+			expectedError: "working directory not found: " + workDir + "/missing",
+		},
+		{
+			name:  "--here skips the directory check",
+			short: "gone",
+			opts:  handler.ExecOptions{Here: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath, err := createTempConfig(configContent)
+			if err != nil {
+				t.Fatalf("failed to create temp config file: %v", err)
+			}
+			defer cleanupTempFile(configPath)
+
+			err = handler.ExecCommand(configPath, "aws", tt.short, tt.opts)
+			if (err != nil && err.Error() != tt.expectedError) || (err == nil && tt.expectedError != "") {
+				t.Errorf("unexpected error: got %v, want %v", err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestExecCommandShell(t *testing.T) {
+	for _, name := range []string{"bash", "python3"} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("%s not available", name)
+		}
+	}
+
+	configContent := `
+[settings]
+shell = "bash"
+
+[commands.sh]
+bashism = '[[ $0 == bash ]]'
+posix = 'test "$0" = sh'
+script = """
+set -e
+count=0
+for i in 1 2 3; do count=$((count + i)); done
+test "$count" -eq 6
+"""
+missing = "true"
+
+[commands.py]
+inline = "import sys; sys.exit(0 if sys.argv[0] == '-c' else 1)"
+script = """
+import sys
+sys.exit(0 if sys.argv[0] != '-c' else 1)
+"""
+
+[meta.sh.posix]
+shell = "sh"
+
+[meta.sh.missing]
+shell = "cmdbook-no-such-shell -c"
+
+[meta.py.inline]
+shell = "python3 -c"
+
+[meta.py.script]
+shell = "python3 -c"
+`
+
+	tests := []struct {
+		name          string
+		prefix        string
+		short         string
+		expectedError string
+	}{
+		{name: "default shell from settings", prefix: "sh", short: "bashism"},
+		{name: "entry shell overrides the default", prefix: "sh", short: "posix"},
+		{name: "multi-line script", prefix: "sh", short: "script"},
+		{name: "interpreter with inline code", prefix: "py", short: "inline"},
+		{name: "interpreter with a script file", prefix: "py", short: "script"},
+		{name: "shell not found", prefix: "sh", short: "missing", expectedError: "shell not found: cmdbook-no-such-shell"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath, err := createTempConfig(configContent)
+			if err != nil {
+				t.Fatalf("failed to create temp config file: %v", err)
+			}
+			defer cleanupTempFile(configPath)
+
+			err = handler.ExecCommand(configPath, tt.prefix, tt.short, handler.ExecOptions{})
+			if (err != nil && err.Error() != tt.expectedError) || (err == nil && tt.expectedError != "") {
+				t.Errorf("unexpected error: got %v, want %v", err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestExecCommandTimeoutAndRetry(t *testing.T) {
+	counter := t.TempDir() + "/attempts"
+
+	configContent := `
+[commands.net]
+sleep = "sleep 5 & wait"
+flaky = 'n=$(cat ` + counter + ` 2>/dev/null || echo 0); n=$((n + 1)); echo $n > ` + counter + `; test $n -ge 3'
+fail = "exit 1"
+usage = "exit 2"
+
+[meta.net.sleep]
+timeout = "100ms"
+
+[meta.net.usage]
+retry = 3
+retry_on = ["1", "timeout"]
+`
+
+	tests := []struct {
+		name          string
+		short         string
+		opts          handler.ExecOptions
+		expectedError string
+	}{
+		{
+			name:          "entry timeout kills the process group",
+			short:         "sleep",
+			expectedError: "timed out after 100ms",
+		},
+		{
+			name:          "flag timeout overrides the entry",
+			short:         "sleep",
+			opts:          handler.ExecOptions{Timeout: 50 * time.Millisecond},
+			expectedError: "timed out after 50ms",
+		},
+		{
+			name:  "retries until the command succeeds",
+			short: "flaky",
+			opts:  handler.ExecOptions{Retry: 3, RetryDelay: time.Millisecond},
+		},
+		{
+			name:          "gives up after the last retry",
+			short:         "fail",
+			opts:          handler.ExecOptions{Retry: 2, RetryDelay: time.Millisecond},
+			expectedError: "failed after 3 attempts: exit status 1",
+		},
+		{
+			name:          "exit codes not listed in retry_on are not retried",
+			short:         "usage",
+			expectedError: "exit status 2",
+		},
+		{
+			name:          "invalid retry condition",
+			short:         "fail",
+			opts:          handler.ExecOptions{Retry: 1, RetryOn: []string{"flaky"}},
+			expectedError: "invalid retry condition 'flaky': use exit codes or timeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath, err := createTempConfig(configContent)
+			if err != nil {
+				t.Fatalf("failed to create temp config file: %v", err)
+			}
+			defer cleanupTempFile(configPath)
+
+			start := time.Now()
+			err = handler.ExecCommand(configPath, "net", tt.short, tt.opts)
+			if (err != nil && err.Error() != tt.expectedError) || (err == nil && tt.expectedError != "") {
+				t.Errorf("unexpected error: got %v, want %v", err, tt.expectedError)
+			}
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Errorf("took %s; the process group was not killed", elapsed)
+			}
+		})
+	}
+}
+
+func TestExecCommandArgs(t *testing.T) {
+	const argsError = `arguments cannot be appended to a command ending in an operator or comment; place them with "$@"`
+	configPath, err := createTempConfig(`
+[commands.say]
+echo = "echo hello"
+noted = "echo hello # greets"
+chained = "echo hello;"
+placed = 'echo "[$@]" done'
+script = """
+echo "first: $1"
+echo "count: $#"
+"""
+both = "say:echo -> say:script"
+
+[meta.say.both]
+steps = [{ ref = "say:echo" }, { ref = "say:script" }]
+`)
+	if err != nil {
+		t.Fatalf("failed to create temp config file: %v", err)
+	}
+	defer cleanupTempFile(configPath)
+
+	tests := []struct {
+		name          string
+		short         string
+		args          []string
+		expected      string
+		expectedError string
+	}{
+		{name: "appended to the command", short: "echo", args: []string{"big", "it's a world"}, expected: "hello big it's a world\n"},
+		{name: "no arguments", short: "echo", expected: "hello\n"},
+		{name: "placed by the command", short: "placed", args: []string{"a", "b"}, expected: "[a b] done\n"},
+		{name: "refused after a comment", short: "noted", args: []string{"x"}, expectedError: argsError},
+		{name: "refused after an operator", short: "chained", args: []string{"x"}, expectedError: argsError},
+		{name: "passed to a script", short: "script", args: []string{"a b", "c"}, expected: "first: a b\ncount: 2\n"},
+		{name: "refused by workflows", short: "both", args: []string{"x"}, expectedError: "arguments only apply to commands: say both is a workflow"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			output := captureStdout(t, func() {
+				err = handler.ExecCommand(configPath, "say", tt.short, handler.ExecOptions{Args: tt.args})
+			})
+
+			if tt.expectedError != "" {
+				if err == nil || err.Error() != tt.expectedError {
+					t.Errorf("ExecCommand() error = %v, want %q", err, tt.expectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExecCommand() error = %v", err)
+			}
+			if output != tt.expected {
+				t.Errorf("output = %q, want %q", output, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExecCommandTemplate(t *testing.T) {
+	configPath, err := createTempConfig(`
+[commands.say]
+greet = "echo hello {{name}} from {{place}}"
+push = "echo git push {{flags}}"
+both = "say:greet -> say:push"
+
+[meta.say.both]
+steps = [{ ref = "say:greet" }, { ref = "say:push" }]
+`)
+	if err != nil {
+		t.Fatalf("failed to create temp config file: %v", err)
+	}
+	defer cleanupTempFile(configPath)
+
+	tests := []struct {
+		name          string
+		short         string
+		opts          handler.ExecOptions
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "values filled in",
+			short:    "greet",
+			opts:     handler.ExecOptions{Set: []string{"name=ann", "place=the office"}},
+			expected: "hello ann from the office\n",
+		},
+		{
+			name:          "missing value",
+			short:         "greet",
+			opts:          handler.ExecOptions{Set: []string{"name=ann"}},
+			expectedError: "no value for {{place}} in say greet: pass --set NAME=VALUE",
+		},
+		{
+			name:          "invalid assignment",
+			short:         "greet",
+			opts:          handler.ExecOptions{Set: []string{"name"}},
+			expectedError: "invalid --set value 'name': use NAME=VALUE",
+		},
+		{
+			name:          "values are classified with the command",
+			short:         "push",
+			opts:          handler.ExecOptions{Set: []string{"flags=--force"}, Input: strings.NewReader("")},
+			expectedError: "aborted: say push was not confirmed",
+		},
+		{
+			name:     "shared by the steps of a workflow",
+			short:    "both",
+			opts:     handler.ExecOptions{Set: []string{"name=ann", "place=home", "flags=--dry-run"}},
+			expected: "hello ann from home\ngit push --dry-run\n",
+		},
+		{
+			name:          "missing in a step before any step runs",
+			short:         "both",
+			opts:          handler.ExecOptions{Set: []string{"name=ann", "place=home"}},
+			expectedError: "no value for {{flags}} in say push: pass --set NAME=VALUE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			output := captureStdout(t, func() {
+				err = handler.ExecCommand(configPath, "say", tt.short, tt.opts)
+			})
+
+			if tt.expectedError != "" {
+				if err == nil || err.Error() != tt.expectedError {
+					t.Errorf("ExecCommand() error = %v, want %q", err, tt.expectedError)
+				}
+				if output != "" {
+					t.Errorf("output = %q, want nothing to run", output)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExecCommand() error = %v", err)
+			}
+			if output != tt.expected {
+				t.Errorf("output = %q, want %q", output, tt.expected)
+			}
+		})
+	}
+}
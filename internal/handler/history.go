@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/history"
+	"github.com/pHo9UBenaA/cmdbook/pkg/ioutil"
+)
+
+type HistoryOptions struct {
+	Failed bool
+	// Since is an age such as "1d"; older runs are left out.
+	Since  string
+	Prefix string
+	// Limit keeps only the most recent runs when it is positive.
+	Limit int
+	JSON  bool
+}
+
+// HistoryExec prints the recorded runs matching opts, oldest first.
+func HistoryExec(configPath string, opts HistoryOptions) error {
+	filter := history.Filter{Failed: opts.Failed, Prefix: opts.Prefix}
+	if opts.Since != "" {
+		age, err := history.ParseAge(opts.Since)
+		if err != nil {
+			return err
+		}
+		filter.Since = time.Now().Add(-age)
+	}
+
+	records, err := history.Load(configPath, filter)
+	if err != nil {
+		return fmt.Errorf("failed to load history: %w", err)
+	}
+	if opts.Limit > 0 && len(records) > opts.Limit {
+		records = records[len(records)-opts.Limit:]
+	}
+
+	if opts.JSON {
+		if records == nil {
+			records = []history.Record{}
+		}
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode history: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No runs recorded")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTIME\tEXIT\tDURATION\tENTRY\tCOMMAND")
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s %s\t%s\n",
+			r.ID, r.Time.Local().Format("2006-01-02 15:04:05"), formatExit(r),
+			r.Duration().Round(time.Millisecond), r.Prefix, r.Short, ioutil.FirstLine(r.Command))
+	}
+	return w.Flush()
+}
+
+func formatExit(r history.Record) string {
+	if r.Error != "" {
+		return r.Error
+	}
+	return strconv.Itoa(r.ExitCode)
+}
+
+// newRecord starts the record of running j with args, storing the command
+// as it is run.
+func newRecord(j execJob, args []string) history.Record {
+	now := time.Now()
+	rec := history.Record{
+		ID:      history.NewID(now),
+		Time:    now,
+		Prefix:  j.ref.Prefix,
+		Short:   j.ref.Short,
+		Command: withArgs(j.entry.Command, args),
+		Set:     j.vars,
+	}
+	rec.Dir, _ = os.Getwd()
+	rec.Host, _ = os.Hostname()
+	return rec
+}
+
+// recordRun completes rec with the outcome of the run and appends it to
+// the history. Failing to record never fails the run itself.
+func recordRun(configPath string, rec history.Record, err error, stderr io.Writer) {
+	rec.DurationMS = time.Since(rec.Time).Milliseconds()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr) && exitErr.ExitCode() >= 0:
+		rec.ExitCode = exitErr.ExitCode()
+	default:
+		rec.ExitCode = -1
+		rec.Error = err.Error()
+	}
+
+	if err := history.Append(configPath, rec); err != nil {
+		fmt.Fprintln(stderr, "Warning: failed to record history:", err)
+	}
+}
+
+// renameHistory files the runs of renamed entries under their new names,
+// so that their frecency follows them.
+func renameHistory(configPath string, moved map[domain.Ref]domain.Ref) {
+	if err := history.Rename(configPath, moved); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: failed to carry history over:", err)
+	}
+}
@@ -0,0 +1,180 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+	"github.com/pHo9UBenaA/cmdbook/internal/history"
+)
+
+func TestExecRecordsHistory(t *testing.T) {
+	workDir := t.TempDir()
+	configPath, err := createTempConfig(`
+[commands.git]
+st = "true"
+push = "exit 3"
+
+[commands.net]
+slow = "sleep 5"
+
+[meta.git.st]
+dir = "` + workDir + `"
+
+[meta.net.slow]
+timeout = "50ms"
+`)
+	if err != nil {
+		t.Fatalf("failed to create temp config file: %v", err)
+	}
+	defer cleanupTempFile(configPath)
+
+	_ = handler.ExecCommand(configPath, "git", "st", handler.ExecOptions{})
+	_ = handler.ExecCommand(configPath, "git", "push", handler.ExecOptions{})
+	_ = handler.ExecCommand(configPath, "net", "slow", handler.ExecOptions{})
+	_ = handler.ExecCommand(configPath, "git", "missing", handler.ExecOptions{})
+
+	records, err := history.Load(configPath, history.Filter{})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3: %+v", len(records), records)
+	}
+
+	host, _ := os.Hostname()
+	if r := records[0]; r.Prefix != "git" || r.Short != "st" || r.Command != "true" || r.ExitCode != 0 || r.Dir != workDir || r.Host != host || r.ID == "" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	if r := records[1]; r.ExitCode != 3 || r.Error != "" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	if r := records[2]; r.ExitCode != -1 || r.Error != "timed out after 50ms" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+
+	output := captureStdout(t, func() {
+		err = handler.HistoryExec(configPath, handler.HistoryOptions{Failed: true, Prefix: "git", JSON: true})
+	})
+	if err != nil {
+		t.Fatalf("HistoryExec() error = %v", err)
+	}
+
+	var got []history.Record
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, output)
+	}
+	if len(got) != 1 || got[0].Short != "push" {
+		t.Errorf("unexpected records: %+v", got)
+	}
+
+	if err := handler.HistoryExec(configPath, handler.HistoryOptions{Since: "soon"}); err == nil {
+		t.Error("expected an error for an invalid age")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(configPath), ".cmdbook", "history.jsonl")); err != nil {
+		t.Errorf("history file missing: %v", err)
+	}
+}
+
+func TestExecRecordsArgs(t *testing.T) {
+	configPath, err := createTempConfig(`
+[commands.p]
+s = "echo hi"
+`)
+	if err != nil {
+		t.Fatalf("failed to create temp config file: %v", err)
+	}
+	defer cleanupTempFile(configPath)
+
+	captureStdout(t, func() {
+		err = handler.ExecCommand(configPath, "p", "s", handler.ExecOptions{Args: []string{"x", "y"}})
+	})
+	if err != nil {
+		t.Fatalf("ExecCommand() error = %v", err)
+	}
+
+	records, err := history.Load(configPath, history.Filter{})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(records) != 1 || records[0].Command != "echo hi x y" {
+		t.Errorf("unexpected records: %+v", records)
+	}
+}
+
+func TestRenameKeepsHistory(t *testing.T) {
+	configPath, err := createTempConfig(`
+[commands.p]
+s = "true"
+`)
+	if err != nil {
+		t.Fatalf("failed to create temp config file: %v", err)
+	}
+	defer cleanupTempFile(configPath)
+
+	captureStdout(t, func() {
+		if err = handler.ExecCommand(configPath, "p", "s", handler.ExecOptions{}); err != nil {
+			return
+		}
+		if err = handler.UpdateCommand(configPath, "p", "s", "", "t", "", handler.UpdateOptions{}); err != nil {
+			return
+		}
+		err = handler.MoveCommands(configPath, domain.Selector{Prefix: "p"}, "q", handler.BulkOptions{Yes: true})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	scores := history.Scores(configPath)
+	if scores[domain.Ref{Prefix: "q", Short: "t"}] == 0 {
+		t.Errorf("q t has no score after the rename: %v", scores)
+	}
+	if _, ok := scores[domain.Ref{Prefix: "p", Short: "s"}]; ok {
+		t.Errorf("p s still has a score: %v", scores)
+	}
+}
+
+func TestTemplateVars(t *testing.T) {
+	configPath, err := createTempConfig(`
+[commands.k]
+logs = "echo logs {{pod}} -n {{ns}}"
+other = "echo {{unrelated}}"
+`)
+	if err != nil {
+		t.Fatalf("failed to create temp config file: %v", err)
+	}
+	defer cleanupTempFile(configPath)
+
+	captureStdout(t, func() {
+		for _, set := range [][]string{{"pod=web-0", "ns=prod"}, {"pod=web-1", "ns=prod"}} {
+			if err = handler.ExecCommand(configPath, "k", "logs", handler.ExecOptions{Set: set}); err != nil {
+				return
+			}
+		}
+		err = handler.ExecCommand(configPath, "k", "other", handler.ExecOptions{Set: []string{"unrelated=x"}})
+	})
+	if err != nil {
+		t.Fatalf("ExecCommand() error = %v", err)
+	}
+
+	records, err := history.Load(configPath, history.Filter{})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(records) != 3 || records[0].Command != "echo logs web-0 -n prod" || records[0].Set["pod"] != "web-0" {
+		t.Errorf("unexpected records: %+v", records)
+	}
+
+	names, values := handler.TemplateVars(configPath, []domain.Ref{{Prefix: "k", Short: "logs"}})
+	if !reflect.DeepEqual(names, []string{"pod", "ns"}) {
+		t.Errorf("names = %v, want [pod ns]", names)
+	}
+	want := map[string][]string{"pod": {"web-1", "web-0"}, "ns": {"prod"}}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %v, want %v", values, want)
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/signature"
+)
+
+const selfKeyName = "self"
+
+// KeysGen creates a signing key pair and trusts its public key.
+func KeysGen(configPath string, force bool) error {
+	pub, err := signature.GenerateKey(configPath, force)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	if err := trustKey(configPath, selfKeyName, signature.EncodeKey(pub)); err != nil {
+		return err
+	}
+
+	fmt.Printf("Generated key: %s\n", signature.PublicKeyPath(configPath))
+	fmt.Println(signature.EncodeKey(pub))
+	return nil
+}
+
+func KeysSign(configPath, bookPath string) error {
+	sigPath, err := signature.SignFile(configPath, bookPath)
+	if err != nil {
+		return fmt.Errorf("failed to sign %s: %w", bookPath, err)
+	}
+
+	fmt.Printf("Signed: %s -> %s\n", bookPath, sigPath)
+	return nil
+}
+
+// KeysTrust trusts key under name. key is either an encoded public key or
+// the path of a public key file.
+func KeysTrust(configPath, name, key string) error {
+	if data, err := os.ReadFile(key); err == nil {
+		key = string(data)
+	}
+
+	pub, err := signature.DecodeKey(key)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+
+	if err := trustKey(configPath, name, signature.EncodeKey(pub)); err != nil {
+		return err
+	}
+
+	fmt.Printf("Trusted: %s\n", name)
+	return nil
+}
+
+func KeysUntrust(configPath, name string) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if _, exists := cfg.TrustedKeys[name]; !exists {
+		return fmt.Errorf("trusted key not found: %s", name)
+	}
+	delete(cfg.TrustedKeys, name)
+
+	if err := saveConfig(cfg, configPath, fmt.Sprintf("keys untrust %s", name)); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	fmt.Printf("Untrusted: %s\n", name)
+	return nil
+}
+
+func KeysList(configPath string) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if len(cfg.TrustedKeys) == 0 {
+		fmt.Println("No trusted keys")
+		return nil
+	}
+
+	names := make([]string, 0, len(cfg.TrustedKeys))
+	for name := range cfg.TrustedKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Printf("%s\t%s\n", name, cfg.TrustedKeys[name])
+	}
+	return nil
+}
+
+func trustKey(configPath, name, key string) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if cfg.TrustedKeys == nil {
+		cfg.TrustedKeys = make(map[string]string)
+	}
+	cfg.TrustedKeys[name] = key
+
+	if err := saveConfig(cfg, configPath, fmt.Sprintf("keys trust %s", name)); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+)
+
+func TestSignedRemoteBooks(t *testing.T) {
+	publishDir := t.TempDir()
+	publisherConfig := filepath.Join(publishDir, "config.toml")
+	bookPath := filepath.Join(publishDir, "team.toml")
+
+	if err := os.WriteFile(bookPath, []byte(`
+[commands.docker]
+up = "echo team docker up"
+`), 0644); err != nil {
+		t.Fatalf("failed to write book: %v", err)
+	}
+
+	captureStdout(t, func() {
+		if err := handler.KeysGen(publisherConfig, false); err != nil {
+			t.Fatalf("KeysGen() error = %v", err)
+		}
+		if err := handler.KeysSign(publisherConfig, bookPath); err != nil {
+			t.Fatalf("KeysSign() error = %v", err)
+		}
+	})
+
+	server := httptest.NewServer(http.FileServer(http.Dir(publishDir)))
+	defer server.Close()
+
+	t.Run("trust on first use pins the key", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "config.toml")
+
+		captureStdout(t, func() {
+			if err := handler.RemoteAdd(configPath, "team", config.Remote{URL: server.URL + "/team.toml", TrustOnFirstUse: true}); err != nil {
+				t.Fatalf("RemoteAdd() error = %v", err)
+			}
+			if err := handler.RemoteUpdate(configPath, nil); err != nil {
+				t.Fatalf("RemoteUpdate() error = %v", err)
+			}
+		})
+
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			t.Fatalf("failed to load config: %v", err)
+		}
+		if !strings.HasPrefix(cfg.Remotes["team"].Key, "ed25519:") {
+			t.Fatalf("key was not pinned: %+v", cfg.Remotes["team"])
+		}
+
+		if err := handler.ExecCommand(configPath, "team:docker", "up", handler.ExecOptions{}); err != nil {
+			t.Errorf("ExecCommand() error = %v", err)
+		}
+	})
+
+	t.Run("untrusted signer is refused until trusted", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "config.toml")
+
+		captureStdout(t, func() {
+			if err := handler.RemoteAdd(configPath, "team", config.Remote{URL: server.URL + "/team.toml"}); err != nil {
+				t.Fatalf("RemoteAdd() error = %v", err)
+			}
+		})
+
+		out := captureStdout(t, func() {
+			if err := handler.RemoteUpdate(configPath, nil); err == nil {
+				t.Error("RemoteUpdate() of a book signed by an untrusted key should fail")
+			}
+		})
+		if !strings.Contains(out, "untrusted key") {
+			t.Errorf("unexpected output %q, want untrusted key", out)
+		}
+		if err := handler.ExecCommand(configPath, "team:docker", "up", handler.ExecOptions{}); err == nil {
+			t.Fatal("ExecCommand() of a rejected book should fail")
+		}
+
+		pubPath := filepath.Join(publishDir, ".cmdbook", "keys", "cmdbook_ed25519.pub")
+		captureStdout(t, func() {
+			if err := handler.KeysTrust(configPath, "publisher", pubPath); err != nil {
+				t.Fatalf("KeysTrust() error = %v", err)
+			}
+			if err := handler.RemoteUpdate(configPath, nil); err != nil {
+				t.Fatalf("RemoteUpdate() after trusting error = %v", err)
+			}
+		})
+
+		if err := handler.ExecCommand(configPath, "team:docker", "up", handler.ExecOptions{}); err != nil {
+			t.Errorf("ExecCommand() after trusting error = %v", err)
+		}
+	})
+
+	t.Run("tampered book is refused", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "config.toml")
+
+		captureStdout(t, func() {
+			if err := handler.RemoteAdd(configPath, "team", config.Remote{URL: server.URL + "/team.toml", TrustOnFirstUse: true}); err != nil {
+				t.Fatalf("RemoteAdd() error = %v", err)
+			}
+			if err := handler.RemoteUpdate(configPath, nil); err != nil {
+				t.Fatalf("RemoteUpdate() error = %v", err)
+			}
+		})
+
+		cachedBook := filepath.Join(filepath.Dir(configPath), ".cmdbook", "remotes", "team.book")
+		if err := os.WriteFile(cachedBook, []byte("[commands.docker]\nup = \"echo pwned\"\n"), 0644); err != nil {
+			t.Fatalf("failed to tamper with cache: %v", err)
+		}
+
+		err := handler.ExecCommand(configPath, "team:docker", "up", handler.ExecOptions{})
+		if err == nil || !strings.Contains(err.Error(), "signature does not match") {
+			t.Errorf("ExecCommand() error = %v, want signature mismatch", err)
+		}
+	})
+
+	t.Run("unsigned update after a signed one keeps the signed copy", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "config.toml")
+
+		captureStdout(t, func() {
+			if err := handler.RemoteAdd(configPath, "team", config.Remote{URL: server.URL + "/team.toml", TrustOnFirstUse: true}); err != nil {
+				t.Fatalf("RemoteAdd() error = %v", err)
+			}
+			if err := handler.RemoteUpdate(configPath, nil); err != nil {
+				t.Fatalf("RemoteUpdate() error = %v", err)
+			}
+		})
+
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			t.Fatalf("failed to load config: %v", err)
+		}
+		if !cfg.Remotes["team"].RequireSignature {
+			t.Fatalf("signatures were not required after a signed fetch: %+v", cfg.Remotes["team"])
+		}
+
+		if err := os.Remove(bookPath + ".sig"); err != nil {
+			t.Fatalf("failed to drop the signature: %v", err)
+		}
+		if err := os.WriteFile(bookPath, []byte("[commands.docker]\nup = \"echo pwned\"\n"), 0644); err != nil {
+			t.Fatalf("failed to replace book: %v", err)
+		}
+		later := time.Now().Add(time.Hour)
+		if err := os.Chtimes(bookPath, later, later); err != nil {
+			t.Fatalf("failed to touch book: %v", err)
+		}
+		out := captureStdout(t, func() {
+			if err := handler.RemoteUpdate(configPath, nil); err == nil {
+				t.Error("RemoteUpdate() of an unsigned book should fail")
+			}
+		})
+		if !strings.Contains(out, "not signed") {
+			t.Errorf("unexpected output %q, want not signed", out)
+		}
+
+		// The last verified copy stays in use.
+		out = captureStdout(t, func() {
+			err = handler.ExecCommand(configPath, "team:docker", "up", handler.ExecOptions{})
+		})
+		if err != nil || strings.TrimSpace(out) != "team docker up" {
+			t.Errorf("ExecCommand() = %q, %v; want the verified command", out, err)
+		}
+	})
+}
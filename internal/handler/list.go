@@ -2,6 +2,7 @@ package handler
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/eiannone/keyboard"
@@ -9,6 +10,9 @@ import (
 
 	"github.com/pHo9UBenaA/cmdbook/internal/config"
 	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/history"
+	"github.com/pHo9UBenaA/cmdbook/internal/remote"
+	"github.com/pHo9UBenaA/cmdbook/internal/risk"
 	"github.com/pHo9UBenaA/cmdbook/pkg/ioutil"
 )
 
@@ -17,24 +21,75 @@ func ListCommands(configPath string) error {
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
-
-	grouped := domain.GroupCommands(cfg.Commands)
-	entries := domain.PrepareInteractiveEntries(grouped)
+	entries, err := loadViewerEntries(configPath, cfg)
+	if err != nil {
+		return err
+	}
 
 	if len(entries) == 0 {
 		fmt.Println("No commands saved")
 		return nil
 	}
 
-	height, _, _ := term.GetSize(int(os.Stdout.Fd()))
-	pageSize := calculatePageSize(height)
+	fd := int(os.Stdout.Fd())
+	_, height, _ := term.GetSize(fd)
 
 	if err := keyboard.Open(); err != nil {
 		return fmt.Errorf("failed to initialize keyboard input: %w", err)
 	}
 	defer keyboard.Close()
 
-	return runInteractiveViewer(entries, pageSize)
+	v := &viewer{
+		out:      os.Stdout,
+		entries:  entries,
+		pageSize: calculatePageSize(height),
+		width:    ioutil.TerminalWidth(fd),
+		cursor:   -1,
+	}
+	_, _, err = runInteractiveViewer(v)
+	return err
+}
+
+// loadViewerEntries returns the entries of the book and its remotes grouped
+// by prefix, most frecent first. Remotes failing verification are left
+// out, as their commands could not be executed.
+func loadViewerEntries(configPath string, cfg *config.Config) ([]domain.CommandEntry, error) {
+	view, err := remote.WithRemotes(configPath, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	verified := make(map[string]bool, len(cfg.Remotes))
+	for name, r := range cfg.Remotes {
+		verified[name] = remote.Verify(configPath, name, r, cfg.TrustedKeys) == nil
+	}
+	for prefix := range view.Commands {
+		if name, _, ok := remote.SplitNamespace(prefix); ok && !verified[name] {
+			delete(view.Commands, prefix)
+		}
+	}
+
+	grouped := domain.GroupCommands(view.Commands)
+	entries := domain.RankEntries(grouped, history.Scores(configPath))
+	if err := markHighRisk(entries, cfg.RiskRules); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func markHighRisk(entries []domain.CommandEntry, rules []config.RiskRule) error {
+	for i, entry := range entries {
+		if entry.Short == "" {
+			continue
+		}
+
+		assessment, err := risk.Classify(entry.Command, rules)
+		if err != nil {
+			return err
+		}
+		entries[i].HighRisk = assessment.Level >= risk.High
+	}
+	return nil
 }
 
 func calculatePageSize(height int) int {
@@ -45,41 +100,102 @@ func calculatePageSize(height int) int {
 	return pageSize
 }
 
-func runInteractiveViewer(entries []domain.CommandEntry, pageSize int) error {
-	offset := 0
+// viewer shows entries page by page on out. Keys are read from the
+// controlling terminal, so out need not be stdout.
+type viewer struct {
+	out      io.Writer
+	entries  []domain.CommandEntry
+	pageSize int
+	width    int
+	offset   int
+	// cursor is the index of the selected entry when picking, -1 when
+	// only viewing.
+	cursor int
+}
+
+func (v *viewer) picking() bool {
+	return v.cursor >= 0
+}
+
+// runInteractiveViewer shows the entries until the user quits or, when
+// picking, selects an entry with Enter. ok reports whether one was selected.
+func runInteractiveViewer(v *viewer) (selected domain.CommandEntry, ok bool, err error) {
 	for {
-		printInteractiveView(entries, pageSize, offset)
+		printInteractiveView(v)
 
 		char, key, err := keyboard.GetKey()
 		if err != nil {
-			return fmt.Errorf("failed to get key input: %w", err)
+			return domain.CommandEntry{}, false, fmt.Errorf("failed to get key input: %w", err)
 		}
 
 		switch {
-		case shouldScrollUp(key, offset):
-			offset--
-		case shouldScrollDown(key, offset, len(entries), pageSize):
-			offset++
+		case v.picking() && key == keyboard.KeyEnter:
+			return v.entries[v.cursor], true, nil
 		case shouldExit(char, key):
-			return nil
+			return domain.CommandEntry{}, false, nil
+		case v.picking():
+			v.moveCursor(key)
+		case shouldScrollUp(key, v.offset):
+			v.offset--
+		case shouldScrollDown(key, v.offset, len(v.entries), v.pageSize):
+			v.offset++
+		}
+	}
+}
+
+// moveCursor selects the previous or next entry, skipping prefix headers,
+// and scrolls to keep it in view.
+func (v *viewer) moveCursor(key keyboard.Key) {
+	step := 0
+	switch key {
+	case keyboard.KeyArrowUp:
+		step = -1
+	case keyboard.KeyArrowDown:
+		step = 1
+	default:
+		return
+	}
+
+	for i := v.cursor + step; i >= 0 && i < len(v.entries); i += step {
+		if v.entries[i].Short != "" {
+			v.cursor = i
+			break
 		}
 	}
+
+	// Keep the line above the cursor, such as the prefix header of its
+	// group, in view as well.
+	top := v.cursor
+	if top > 0 && v.pageSize > 1 {
+		top--
+	}
+
+	switch {
+	case top < v.offset:
+		v.offset = top
+	case v.cursor >= v.offset+v.pageSize:
+		v.offset = v.cursor - v.pageSize + 1
+	}
 }
 
-func printInteractiveView(entries []domain.CommandEntry, pageSize, offset int) {
-	fmt.Fprint(os.Stdout, "\033[2J\033[H") // clear display
-	printed := ioutil.PrintInteractiveList(entries, pageSize, offset)
-	printFooter(offset, printed, len(entries))
+func printInteractiveView(v *viewer) {
+	fmt.Fprint(v.out, "\033[2J\033[H") // clear display
+	printed := ioutil.PrintInteractiveList(v.out, v.entries, v.width, v.pageSize, v.offset, v.cursor)
+	printFooter(v, printed)
 }
 
-func printFooter(offset, printed, total int) {
-	end := offset + printed
-	if end > total {
-		end = total
+func printFooter(v *viewer, printed int) {
+	end := v.offset + printed
+	if end > len(v.entries) {
+		end = len(v.entries)
+	}
+	keys := "▲/▼ scroll, q quit"
+	if v.picking() {
+		keys = "▲/▼ move, Enter insert, q cancel"
 	}
-	footer := fmt.Sprintf("\nCommands %d-%d of %d (▲/▼ scroll, q quit)",
-		offset+1, end, total)
-	fmt.Fprintln(os.Stdout, footer)
+	footer := fmt.Sprintf("\nCommands %d-%d of %d (%s)",
+		v.offset+1, end, len(v.entries), keys)
+	fmt.Fprintln(v.out, footer)
 }
 
 func shouldScrollUp(key keyboard.Key, offset int) bool {
@@ -91,5 +207,5 @@ func shouldScrollDown(key keyboard.Key, offset, total, pageSize int) bool {
 }
 
 func shouldExit(char rune, key keyboard.Key) bool {
-	return char == 'q' || key == keyboard.KeyEsc
+	return char == 'q' || key == keyboard.KeyEsc || key == keyboard.KeyCtrlC
 }
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/remote"
+)
+
+// lookupCommand finds a stored command, resolving namespaced prefixes such
+// as "team:docker" against the cached book of the remote once its signature
+// has been verified.
+func lookupCommand(configPath string, cfg *config.Config, prefix, short string) (config.Entry, error) {
+	book, bookPrefix := cfg, prefix
+
+	if name, inner, ok := remote.SplitNamespace(prefix); ok {
+		if r, isRemote := cfg.Remotes[name]; isRemote {
+			remoteBook, err := remote.Load(configPath, name)
+			if err != nil {
+				return config.Entry{}, fmt.Errorf("failed to load remote %s: %w", name, err)
+			}
+			if err := remote.Verify(configPath, name, r, cfg.TrustedKeys); err != nil {
+				return config.Entry{}, fmt.Errorf("refusing to use remote %s: %w", name, err)
+			}
+			book, bookPrefix = remoteBook, inner
+		}
+	}
+
+	entry, ok := book.GetEntry(bookPrefix, short)
+	if !ok {
+		return config.Entry{}, fmt.Errorf("command not found: %s %s", prefix, short)
+	}
+	return entry, nil
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+)
+
+// MergeBooks merges base, ours and theirs entry by entry and writes the
+// result to oursPath, so it can be used as a git merge driver (%O %A %B).
+func MergeBooks(basePath, oursPath, theirsPath string) error {
+	books := make([]*config.Config, 0, 3)
+	for _, path := range []string{basePath, oursPath, theirsPath} {
+		cfg, err := config.LoadConfig(path)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", path, err)
+		}
+		books = append(books, cfg)
+	}
+
+	merged, conflicts := config.Merge(books[0], books[1], books[2])
+	if len(conflicts) == 0 {
+		if err := config.SaveConfig(merged, oursPath); err != nil {
+			return fmt.Errorf("failed to save merged book: %w", err)
+		}
+		return nil
+	}
+
+	data, err := config.FormatConflicts(merged, conflicts)
+	if err != nil {
+		return fmt.Errorf("failed to format conflicts: %w", err)
+	}
+	if err := os.WriteFile(oursPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to save merged book: %w", err)
+	}
+
+	for _, c := range conflicts {
+		fmt.Printf("Conflict: %s %s\n", c.Prefix, c.Short)
+	}
+	return fmt.Errorf("%d conflicting entries in %s", len(conflicts), oursPath)
+}
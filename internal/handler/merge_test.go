@@ -0,0 +1,106 @@
+package handler_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+)
+
+func TestMergeBooks(t *testing.T) {
+	tests := []struct {
+		name         string
+		base         string
+		ours         string
+		theirs       string
+		expectError  string
+		expectedCmds map[string]map[string]string
+		expectedText []string
+	}{
+		{
+			name: "merges non-overlapping changes into ours",
+			base: `
+[commands.git]
+st = "git status"
+co = "git checkout"
+`,
+			ours: `
+[commands.git]
+st = "git status -s"
+co = "git checkout"
+`,
+			theirs: `
+[commands.git]
+st = "git status"
+[commands.docker]
+up = "docker compose up"
+`,
+			expectedCmds: map[string]map[string]string{
+				"git":    {"st": "git status -s"},
+				"docker": {"up": "docker compose up"},
+			},
+		},
+		{
+			name: "writes conflict markers when both sides change an entry",
+			base: `
+[commands.git]
+st = "git status"
+`,
+			ours: `
+[commands.git]
+st = "git status -s"
+`,
+			theirs: `
+[commands.git]
+st = "git status -b"
+`,
+			expectError:  "1 conflicting entries",
+			expectedText: []string{"<<<<<<< ours", "st = 'git status -s'", "=======", "st = 'git status -b'", ">>>>>>> theirs"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			paths := make([]string, 0, 3)
+			for i, content := range []string{tt.base, tt.ours, tt.theirs} {
+				path := filepath.Join(dir, []string{"base.toml", "ours.toml", "theirs.toml"}[i])
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write book: %v", err)
+				}
+				paths = append(paths, path)
+			}
+
+			err := handler.MergeBooks(paths[0], paths[1], paths[2])
+			if tt.expectError == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectError != "" && (err == nil || !contains(err.Error(), tt.expectError)) {
+				t.Fatalf("expected error containing %q, got %v", tt.expectError, err)
+			}
+
+			if tt.expectedCmds != nil {
+				cfg, err := config.LoadConfig(paths[1])
+				if err != nil {
+					t.Fatalf("failed to load merged book: %v", err)
+				}
+				if !equalCommands(cfg.Commands, tt.expectedCmds) {
+					t.Errorf("unexpected commands: got %v, want %v", cfg.Commands, tt.expectedCmds)
+				}
+			}
+
+			data, err := os.ReadFile(paths[1])
+			if err != nil {
+				t.Fatalf("failed to read merged book: %v", err)
+			}
+			for _, want := range tt.expectedText {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("merged book missing %q:\n%s", want, data)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/history"
+)
+
+// LastRun names the most recent captured run in ShowOutput.
+const LastRun = "last"
+
+// ShowOutput replays the captured output of the run with the given ID, or
+// of the most recent captured run for LastRun.
+func ShowOutput(configPath, id string) error {
+	records, err := history.Load(configPath, history.Filter{})
+	if err != nil {
+		return fmt.Errorf("failed to load history: %w", err)
+	}
+
+	var rec *history.Record
+	for i := len(records) - 1; i >= 0; i-- {
+		if (id == LastRun && records[i].Output) || records[i].ID == id {
+			rec = &records[i]
+			break
+		}
+	}
+
+	switch {
+	case rec == nil && id == LastRun:
+		return fmt.Errorf("no captured output: run a command with cb exec --capture")
+	case rec == nil:
+		return fmt.Errorf("run not found: %s", id)
+	case !rec.Output:
+		return fmt.Errorf("output of run %s was not captured", rec.ID)
+	}
+
+	file, err := os.Open(history.OutputPath(configPath, rec.ID))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("output of run %s is no longer available", rec.ID)
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fmt.Fprintf(os.Stderr, "Run %s: %s %s (%s, exit %s)\n",
+		rec.ID, rec.Prefix, rec.Short, rec.Time.Local().Format("2006-01-02 15:04:05"), formatExit(*rec))
+	_, err = io.Copy(os.Stdout, file)
+	return err
+}
+
+func finishCapture(configPath string, capture *history.Capture, warnings io.Writer) {
+	if err := capture.Close(); err != nil {
+		fmt.Fprintln(warnings, "Warning: failed to save captured output:", err)
+	}
+	if err := history.CleanupOutputs(configPath); err != nil {
+		fmt.Fprintln(warnings, "Warning: failed to clean up captured outputs:", err)
+	}
+}
@@ -0,0 +1,75 @@
+package handler_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+	"github.com/pHo9UBenaA/cmdbook/internal/history"
+)
+
+func TestCaptureOutput(t *testing.T) {
+	configPath, err := createTempConfig(`
+[commands.test]
+unit = "echo unit ok; echo unit warning >&2"
+lint = "echo lint ok"
+all = "test:unit -> test:lint"
+
+[meta.test.all]
+steps = [{ ref = "test:unit" }, { ref = "test:lint" }]
+`)
+	if err != nil {
+		t.Fatalf("failed to create temp config file: %v", err)
+	}
+	defer cleanupTempFile(configPath)
+
+	if err := handler.ShowOutput(configPath, handler.LastRun); err == nil || !strings.HasPrefix(err.Error(), "no captured output") {
+		t.Errorf("unexpected error before any capture: %v", err)
+	}
+
+	captureStdout(t, func() {
+		_ = handler.ExecCommand(configPath, "test", "unit", handler.ExecOptions{Capture: true})
+		_ = handler.ExecCommand(configPath, "test", "lint", handler.ExecOptions{})
+	})
+
+	output := captureStdout(t, func() { err = handler.ShowOutput(configPath, handler.LastRun) })
+	if err != nil {
+		t.Fatalf("ShowOutput() error = %v", err)
+	}
+	if output != "unit ok\nunit warning\n" && output != "unit warning\nunit ok\n" {
+		t.Errorf("unexpected replay: %q", output)
+	}
+
+	records, _ := history.Load(configPath, history.Filter{})
+	if len(records) != 2 || !records[0].Output || records[1].Output {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+	if err := handler.ShowOutput(configPath, records[1].ID); err == nil || !strings.HasSuffix(err.Error(), "was not captured") {
+		t.Errorf("unexpected error for an uncaptured run: %v", err)
+	}
+	if err := handler.ShowOutput(configPath, "nope"); err == nil || err.Error() != "run not found: nope" {
+		t.Errorf("unexpected error for an unknown run: %v", err)
+	}
+
+	captureStdout(t, func() {
+		_ = handler.ExecCommand(configPath, "test", "all", handler.ExecOptions{Capture: true})
+	})
+	output = captureStdout(t, func() { err = handler.ShowOutput(configPath, handler.LastRun) })
+	if err != nil {
+		t.Fatalf("ShowOutput() error = %v", err)
+	}
+	if !strings.Contains(output, "unit ok") || !strings.Contains(output, "lint ok") {
+		t.Errorf("workflow output should be captured as one run: %q", output)
+	}
+
+	records, _ = history.Load(configPath, history.Filter{})
+	captured := 0
+	for _, r := range records {
+		if r.Output {
+			captured++
+		}
+	}
+	if captured != 2 {
+		t.Errorf("got %d captured runs, want 2 (steps are part of the workflow's)", captured)
+	}
+}
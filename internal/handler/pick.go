@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/eiannone/keyboard"
+	"golang.org/x/term"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/remote"
+	"github.com/pHo9UBenaA/cmdbook/pkg/ioutil"
+)
+
+// ttyPath is the controlling terminal, where the picker is drawn while
+// stdout is captured by the shell.
+const ttyPath = "/dev/tty"
+
+// PickCommand lets the user choose an entry in the interactive viewer and
+// prints its command to stdout, for a shell widget to insert into the
+// prompt. Nothing is printed when the picker is cancelled.
+func PickCommand(configPath string) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	entries, err := loadViewerEntries(configPath, cfg)
+	if err != nil {
+		return err
+	}
+
+	cursor := -1
+	for i, entry := range entries {
+		if entry.Short != "" {
+			cursor = i
+			break
+		}
+	}
+	if cursor < 0 {
+		return fmt.Errorf("no commands saved")
+	}
+
+	tty, err := os.OpenFile(ttyPath, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open terminal: %w", err)
+	}
+	defer tty.Close()
+
+	fd := int(tty.Fd())
+	_, height, _ := term.GetSize(fd)
+
+	if err := keyboard.Open(); err != nil {
+		return fmt.Errorf("failed to initialize keyboard input: %w", err)
+	}
+	defer keyboard.Close()
+
+	// Draw on the alternate screen so that the prompt is left as it was.
+	fmt.Fprint(tty, "\033[?1049h")
+	v := &viewer{
+		out:      tty,
+		entries:  entries,
+		pageSize: calculatePageSize(height),
+		width:    ioutil.TerminalWidth(fd),
+		cursor:   cursor,
+	}
+	selected, ok, err := runInteractiveViewer(v)
+	fmt.Fprint(tty, "\033[?1049l")
+	if err != nil || !ok {
+		return err
+	}
+
+	fmt.Println(insertedCommand(configPath, cfg, selected))
+	return nil
+}
+
+// insertedCommand is what the picker inserts for entry: its command when
+// running it as typed behaves like cb exec, or else a call to cb exec. That
+// is the case for entries of remotes, workflows, whose command only
+// describes their steps, entries with a directory, environment or shell of
+// their own and commands with template variables to fill in.
+func insertedCommand(configPath string, cfg *config.Config, entry domain.CommandEntry) string {
+	call := fmt.Sprintf("cb exec %s %s", entry.Prefix, entry.Short)
+	if name, _, ok := remote.SplitNamespace(entry.Prefix); ok {
+		if _, isRemote := cfg.Remotes[name]; isRemote {
+			return call
+		}
+	}
+
+	stored, err := lookupCommand(configPath, cfg, entry.Prefix, entry.Short)
+	if err != nil || stored.IsWorkflow() || stored.Meta.Dir != "" || len(stored.Meta.Env) > 0 || stored.Meta.Shell != "" {
+		return call
+	}
+	if vars := domain.TemplateVars(stored.Command); len(vars) > 0 {
+		return call + " --set " + strings.Join(vars, "= --set ") + "="
+	}
+	return stored.Command
+}
@@ -0,0 +1,117 @@
+package handler_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+)
+
+// TestTimeoutOnTerminal runs a command reading from a terminal with a
+// timeout. The test re-runs itself in a session whose controlling terminal
+// is a new pseudo-terminal, so that cb runs in its foreground.
+func TestTimeoutOnTerminal(t *testing.T) {
+	pidFile := os.Getenv("CMDBOOK_TEST_PID_FILE")
+	if pidFile != "" {
+		runTimeoutOnTerminal(t, pidFile)
+		return
+	}
+
+	tty, err := openPseudoTerminal(t)
+	if err != nil {
+		t.Skipf("no pseudo-terminal: %v", err)
+	}
+
+	pidFile = filepath.Join(t.TempDir(), "pid")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	child := exec.CommandContext(ctx, os.Args[0], "-test.run=^TestTimeoutOnTerminal$", "-test.v")
+	child.Env = append(os.Environ(), "CMDBOOK_TEST_PID_FILE="+pidFile)
+	child.Stdin = tty
+	child.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+	if out, err := child.CombinedOutput(); err != nil {
+		t.Fatalf("command on the terminal failed: %v\n%s", err, out)
+	}
+
+}
+
+func runTimeoutOnTerminal(t *testing.T, pidFile string) {
+	configPath, err := createTempConfig(`
+[commands.net]
+slow = "sleep 30 & echo $! > ` + pidFile + `; sleep 31; echo done"
+`)
+	if err != nil {
+		t.Fatalf("failed to create temp config file: %v", err)
+	}
+	defer cleanupTempFile(configPath)
+
+	err = handler.ExecCommand(configPath, "net", "slow", handler.ExecOptions{Timeout: 200 * time.Millisecond})
+	if err == nil || err.Error() != "timed out after 200ms" {
+		t.Errorf("ExecCommand() error = %v, want a timeout", err)
+	}
+
+	// Checked before this process exits, which hangs up the terminal and
+	// with it whatever is left in its foreground group.
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("the command did not start its children: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatalf("invalid pid %q", data)
+	}
+	for deadline := time.Now().Add(2 * time.Second); processAlive(pid); time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("process %d started by the timed-out command is still running", pid)
+		}
+	}
+
+	pgrp, err := unix.IoctlGetInt(0, unix.TIOCGPGRP)
+	if err != nil || pgrp != unix.Getpgrp() {
+		t.Errorf("the terminal was not handed back: foreground group %d, want %d (%v)", pgrp, unix.Getpgrp(), err)
+	}
+}
+
+// openPseudoTerminal returns the terminal side of a new pseudo-terminal.
+func openPseudoTerminal(t *testing.T) (*os.File, error) {
+	ptmx, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() { ptmx.Close() })
+
+	if err := unix.IoctlSetPointerInt(int(ptmx.Fd()), unix.TIOCSPTLCK, 0); err != nil {
+		return nil, err
+	}
+	n, err := unix.IoctlGetInt(int(ptmx.Fd()), unix.TIOCGPTN)
+	if err != nil {
+		return nil, err
+	}
+	tty, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() { tty.Close() })
+	return tty, nil
+}
+
+// processAlive reports whether pid is running; zombies count as gone.
+func processAlive(pid int) bool {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
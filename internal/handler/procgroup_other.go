@@ -0,0 +1,10 @@
+//go:build !unix
+
+package handler
+
+import "os/exec"
+
+// setProcessGroup is a no-op; cancelling cmd kills only the process itself.
+func setProcessGroup(cmd *exec.Cmd) (restore func()) {
+	return func() {}
+}
@@ -0,0 +1,55 @@
+//go:build unix
+
+package handler
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// setProcessGroup starts cmd in its own process group and makes cancelling
+// it kill the whole group, including processes started by the shell. When
+// cmd reads from the terminal cb runs in the foreground of, the group is
+// handed the terminal, as a shell does with a foreground job; the returned
+// restore takes it back once cmd has exited.
+func setProcessGroup(cmd *exec.Cmd) (restore func()) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	fd, ok := foregroundTerminal(cmd.Stdin)
+	if !ok {
+		return func() {}
+	}
+	cmd.SysProcAttr.Foreground = true
+	cmd.SysProcAttr.Ctty = fd
+	return func() { reclaimTerminal(fd) }
+}
+
+// foregroundTerminal returns the descriptor of stdin when it is a terminal
+// whose foreground process group is the one of cb.
+func foregroundTerminal(stdin io.Reader) (int, bool) {
+	f, ok := stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return 0, false
+	}
+	fd := int(f.Fd())
+	pgrp, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP)
+	return fd, err == nil && pgrp == unix.Getpgrp()
+}
+
+// reclaimTerminal makes the process group of cb the foreground group of the
+// terminal again. Doing so from the background raises SIGTTOU, which is
+// ignored meanwhile.
+func reclaimTerminal(fd int) {
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	_ = unix.IoctlSetPointerInt(fd, unix.TIOCSPGRP, unix.Getpgrp())
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/remote"
+	"github.com/pHo9UBenaA/cmdbook/internal/signature"
+)
+
+func RemoteAdd(configPath, name string, r config.Remote) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if err := namePolicy(cfg).ValidateRemote(name); err != nil {
+		return err
+	}
+
+	if _, exists := cfg.Remotes[name]; exists {
+		return fmt.Errorf("remote already exists: %s", name)
+	}
+
+	if cfg.Remotes == nil {
+		cfg.Remotes = make(map[string]config.Remote)
+	}
+	if r.Key != "" {
+		key, err := signature.DecodeKey(r.Key)
+		if err != nil {
+			return fmt.Errorf("invalid key for remote %s: %w", name, err)
+		}
+		r.Key = signature.EncodeKey(key)
+	}
+	cfg.Remotes[name] = r
+
+	if err := saveConfig(cfg, configPath, fmt.Sprintf("remote add %s", name)); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	fmt.Printf("Added remote: %s -> %s\n", name, r.URL)
+	return nil
+}
+
+func RemoteRemove(configPath, name string) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if _, exists := cfg.Remotes[name]; !exists {
+		return fmt.Errorf("remote not found: %s", name)
+	}
+	delete(cfg.Remotes, name)
+
+	if err := saveConfig(cfg, configPath, fmt.Sprintf("remote remove %s", name)); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+	if err := remote.Remove(configPath, name); err != nil {
+		return fmt.Errorf("failed to remove cached book: %w", err)
+	}
+
+	fmt.Printf("Removed remote: %s\n", name)
+	return nil
+}
+
+func RemoteList(configPath string) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if len(cfg.Remotes) == 0 {
+		fmt.Println("No remotes configured")
+		return nil
+	}
+
+	for _, name := range remoteNames(cfg) {
+		fmt.Printf("%s\t%s\n", name, cfg.Remotes[name].URL)
+	}
+	return nil
+}
+
+// RemoteUpdate refreshes the cached books of the named remotes, or of all
+// remotes when names is empty.
+func RemoteUpdate(configPath string, names []string) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if len(names) == 0 {
+		names = remoteNames(cfg)
+	}
+
+	var failed int
+	for _, name := range names {
+		r, exists := cfg.Remotes[name]
+		if !exists {
+			return fmt.Errorf("remote not found: %s", name)
+		}
+
+		updated, err := remote.Update(remote.DefaultClient, configPath, name, r, cfg.TrustedKeys)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Printf("Failed: %s: %v\n", name, err)
+			continue
+		case updated:
+			fmt.Printf("Updated: %s\n", name)
+		default:
+			fmt.Printf("Up to date: %s\n", name)
+		}
+
+		if r.TrustOnFirstUse && r.Key == "" {
+			if err := pinRemoteKey(configPath, cfg, name); err != nil {
+				failed++
+				fmt.Printf("Failed: %s: %v\n", name, err)
+				continue
+			}
+		}
+
+		if !cfg.Remotes[name].RequireSignature && remote.Signed(configPath, name) {
+			if err := requireSignature(configPath, cfg, name); err != nil {
+				failed++
+				fmt.Printf("Failed: %s: %v\n", name, err)
+				continue
+			}
+		}
+
+		if err := remote.Verify(configPath, name, cfg.Remotes[name], cfg.TrustedKeys); err != nil {
+			fmt.Printf("Warning: %s: %v; its commands will not be executed\n", name, err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to update %d remote(s)", failed)
+	}
+	return nil
+}
+
+func pinRemoteKey(configPath string, cfg *config.Config, name string) error {
+	key, err := remote.FirstUseKey(configPath, name)
+	if err != nil {
+		return fmt.Errorf("cannot pin key: %w", err)
+	}
+
+	r := cfg.Remotes[name]
+	r.Key = key
+	cfg.Remotes[name] = r
+
+	if err := saveConfig(cfg, configPath, fmt.Sprintf("remote pin %s", name)); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	fmt.Printf("Pinned key for %s: %s\n", name, key)
+	return nil
+}
+
+// requireSignature makes remote name refuse unsigned books once it has
+// served a signed one, so that dropping the signature from a later update
+// does not get its commands executed.
+func requireSignature(configPath string, cfg *config.Config, name string) error {
+	r := cfg.Remotes[name]
+	r.RequireSignature = true
+	cfg.Remotes[name] = r
+
+	if err := saveConfig(cfg, configPath, fmt.Sprintf("remote %s requires signatures", name)); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	fmt.Printf("Signatures are now required for %s\n", name)
+	return nil
+}
+
+func remoteNames(cfg *config.Config) []string {
+	names := make([]string, 0, len(cfg.Remotes))
+	for name := range cfg.Remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
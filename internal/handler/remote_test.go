@@ -0,0 +1,131 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+	"github.com/pHo9UBenaA/cmdbook/internal/signature"
+)
+
+func TestRemoteBooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, signature.Ext) {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`
+[commands.docker]
+up = "echo team docker up"
+`))
+	}))
+	defer server.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+
+	captureStdout(t, func() {
+		if err := handler.RemoteAdd(configPath, "team", config.Remote{URL: server.URL + "/team.toml"}); err != nil {
+			t.Fatalf("RemoteAdd() error = %v", err)
+		}
+	})
+
+	if err := handler.RemoteAdd(configPath, "team", config.Remote{URL: server.URL}); err == nil || err.Error() != "remote already exists: team" {
+		t.Errorf("duplicate RemoteAdd() error = %v", err)
+	}
+	if err := handler.RemoteAdd(configPath, "my team", config.Remote{URL: server.URL}); err == nil {
+		t.Error("expected an error for an invalid remote name")
+	}
+
+	if err := handler.ExecCommand(configPath, "team:docker", "up", handler.ExecOptions{}); err == nil {
+		t.Error("expected an error before the remote is fetched")
+	}
+
+	captureStdout(t, func() {
+		if err := handler.RemoteUpdate(configPath, nil); err != nil {
+			t.Fatalf("RemoteUpdate() error = %v", err)
+		}
+	})
+
+	if err := handler.ExecCommand(configPath, "team:docker", "up", handler.ExecOptions{}); err != nil {
+		t.Errorf("ExecCommand() on remote entry error = %v", err)
+	}
+	if err := handler.ExecCommand(configPath, "team:docker", "down", handler.ExecOptions{}); err == nil || err.Error() != "command not found: team:docker down" {
+		t.Errorf("ExecCommand() on missing remote entry error = %v", err)
+	}
+
+	if err := handler.RemoveCommand(configPath, "team:docker", "up"); err == nil {
+		t.Error("remote entries must not be removable")
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if len(cfg.Commands) != 0 {
+		t.Errorf("remote entries were written to the local book: %v", cfg.Commands)
+	}
+
+	captureStdout(t, func() {
+		if err := handler.RemoteRemove(configPath, "team"); err != nil {
+			t.Fatalf("RemoteRemove() error = %v", err)
+		}
+	})
+	if err := handler.RemoteUpdate(configPath, []string{"team"}); err == nil || err.Error() != "remote not found: team" {
+		t.Errorf("RemoteUpdate() after removal error = %v", err)
+	}
+}
+
+func TestRemoteWorkflowSteps(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, signature.Ext) {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`
+[commands.docker]
+build = "echo team > ` + out + `"
+
+[commands.release]
+deploy = "docker:build"
+
+[[meta.release.deploy.steps]]
+ref = "docker:build"
+`))
+	}))
+	defer server.Close()
+
+	configPath, err := createTempConfig(`
+[commands.docker]
+build = "echo local > ` + out + `"
+`)
+	if err != nil {
+		t.Fatalf("failed to create temp config: %v", err)
+	}
+	defer cleanupTempFile(configPath)
+
+	captureStdout(t, func() {
+		if err := handler.RemoteAdd(configPath, "team", config.Remote{URL: server.URL + "/team.toml"}); err != nil {
+			t.Fatalf("RemoteAdd() error = %v", err)
+		}
+		if err := handler.RemoteUpdate(configPath, nil); err != nil {
+			t.Fatalf("RemoteUpdate() error = %v", err)
+		}
+	})
+
+	if err := handler.ExecCommand(configPath, "team:release", "deploy", handler.ExecOptions{}); err != nil {
+		t.Fatalf("ExecCommand() error = %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("step did not run: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "team" {
+		t.Errorf("the step ran the %s command, want the remote one", got)
+	}
+}
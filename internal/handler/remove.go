@@ -21,12 +21,9 @@ func RemoveCommand(configPath, prefix, shortCmd string) error {
 		return fmt.Errorf("command not found: %s %s", prefix, shortCmd)
 	}
 
-	delete(cmds, shortCmd)
-	if len(cmds) == 0 {
-		delete(cfg.Commands, prefix)
-	}
+	cfg.DeleteEntry(prefix, shortCmd)
 
-	if err := config.SaveConfig(cfg, configPath); err != nil {
+	if err := saveConfig(cfg, configPath, fmt.Sprintf("remove %s %s", prefix, shortCmd)); err != nil {
 		return fmt.Errorf("failed to save configuration: %w", err)
 	}
 
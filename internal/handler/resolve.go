@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/history"
+	"github.com/pHo9UBenaA/cmdbook/internal/remote"
+)
+
+// maxSuggestions caps the entries offered when a name does not match.
+const maxSuggestions = 5
+
+// resolveRef finds the entry named by prefix and short. An empty prefix
+// looks short up across every prefix, including those of remote books.
+// When nothing matches exactly, the closest entries are offered in a picker
+// on a terminal and listed in the error otherwise.
+func resolveRef(configPath string, cfg *config.Config, prefix, short string, opts ExecOptions) (domain.Ref, error) {
+	view, err := remote.WithRemotes(configPath, cfg)
+	if err != nil {
+		return domain.Ref{}, err
+	}
+
+	if prefix != "" {
+		ref := domain.Ref{Prefix: prefix, Short: short}
+		if _, ok := view.Commands[prefix][short]; ok {
+			return ref, nil
+		}
+		suggestions := suggestRefs(ref, RankedRefs(configPath, view))
+		if len(suggestions) == 0 {
+			// Leave the error, such as an unfetched remote, to the lookup.
+			return ref, nil
+		}
+		return pickRef(fmt.Sprintf("command not found: %s %s", prefix, short), suggestions, opts)
+	}
+
+	refs := RankedRefs(configPath, view)
+	var matches []domain.Ref
+	for _, ref := range refs {
+		if ref.Short == short {
+			matches = append(matches, ref)
+		}
+	}
+
+	switch {
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(matches) > 1:
+		return pickRef(fmt.Sprintf("ambiguous command: %s is saved under %d prefixes", short, len(matches)), matches, opts)
+	}
+
+	suggestions := domain.Suggest(short, refs, func(ref domain.Ref) string { return ref.Short })
+	return pickRef("command not found: "+short, suggestions, opts)
+}
+
+// suggestRefs finds the entries ref may have been meant as: the shorts
+// close to its own under a known prefix, or else the closest prefix:short
+// pairs overall.
+func suggestRefs(ref domain.Ref, refs []domain.Ref) []domain.Ref {
+	var siblings []domain.Ref
+	for _, r := range refs {
+		if r.Prefix == ref.Prefix {
+			siblings = append(siblings, r)
+		}
+	}
+	if len(siblings) > 0 {
+		return domain.Suggest(ref.Short, siblings, func(r domain.Ref) string { return r.Short })
+	}
+	return domain.Suggest(ref.String(), refs, domain.Ref.String)
+}
+
+// RankedRefs lists every entry of view, most frecent first, ties broken by
+// name.
+func RankedRefs(configPath string, view *config.Config) []domain.Ref {
+	var refs []domain.Ref
+	for prefix, cmds := range view.Commands {
+		for short := range cmds {
+			refs = append(refs, domain.Ref{Prefix: prefix, Short: short})
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+	history.Rank(refs, history.Scores(configPath))
+	return refs
+}
+
+// pickRef lets the user choose one of candidates. Without a terminal to ask
+// on, problem is returned along with the candidates.
+func pickRef(problem string, candidates []domain.Ref, opts ExecOptions) (domain.Ref, error) {
+	if len(candidates) > maxSuggestions {
+		candidates = candidates[:maxSuggestions]
+	}
+	if len(candidates) == 0 {
+		return domain.Ref{}, fmt.Errorf("%s", problem)
+	}
+
+	input, ok := promptInput(opts.Input)
+	if !ok {
+		return domain.Ref{}, fmt.Errorf("%s: did you mean %s?", problem, formatCandidates(candidates))
+	}
+
+	fmt.Fprintf(os.Stderr, "%s\nDid you mean:\n", strings.ToUpper(problem[:1])+problem[1:])
+	for i, ref := range candidates {
+		fmt.Fprintf(os.Stderr, "  %d) %s %s\n", i+1, ref.Prefix, ref.Short)
+	}
+	fmt.Fprintf(os.Stderr, "Select [1-%d], or press Enter to cancel: ", len(candidates))
+
+	answer, err := readLine(input)
+	if err != nil && err != io.EOF {
+		return domain.Ref{}, fmt.Errorf("failed to read selection: %w", err)
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return domain.Ref{}, fmt.Errorf("aborted: no command selected")
+	}
+
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 || n > len(candidates) {
+		return domain.Ref{}, fmt.Errorf("invalid selection: %s", answer)
+	}
+	return candidates[n-1], nil
+}
+
+func formatCandidates(refs []domain.Ref) string {
+	names := make([]string, len(refs))
+	for i, ref := range refs {
+		names[i] = ref.Prefix + " " + ref.Short
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,91 @@
+package handler_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/term"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+)
+
+func TestExecCommandResolve(t *testing.T) {
+	configPath, err := createTempConfig(`
+[commands.git]
+push-main = "echo git push-main"
+pull = "echo git pull"
+
+[commands.gh]
+pull = "echo gh pull"
+`)
+	if err != nil {
+		t.Fatalf("failed to create temp config file: %v", err)
+	}
+	defer cleanupTempFile(configPath)
+
+	tests := []struct {
+		name          string
+		prefix        string
+		short         string
+		input         string
+		expected      string
+		expectedError string
+	}{
+		{name: "unique short", short: "push-main", expected: "git push-main"},
+		{name: "pick among prefixes", short: "pull", input: "1\n", expected: "gh pull"},
+		{name: "pick a suggestion", short: "push-mian", input: "1\n", expected: "git push-main"},
+		{name: "pick a suggestion within prefix", prefix: "git", short: "pul", input: "1\n", expected: "git pull"},
+		{name: "pick a misspelled prefix", prefix: "gti", short: "push-main", input: "1\n", expected: "git push-main"},
+		{name: "cancel the picker", short: "pull", input: "\n", expectedError: "aborted: no command selected"},
+		{name: "invalid selection", short: "pull", input: "3\n", expectedError: "invalid selection: 3"},
+		{name: "nothing close", short: "deploy", expectedError: "command not found: deploy"},
+		{name: "nothing close within prefix", prefix: "git", short: "deploy", expectedError: "command not found: git deploy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := handler.ExecOptions{}
+			if tt.input != "" {
+				opts.Input = strings.NewReader(tt.input)
+			}
+
+			var err error
+			output := captureStdout(t, func() {
+				err = handler.ExecCommand(configPath, tt.prefix, tt.short, opts)
+			})
+
+			if tt.expectedError != "" {
+				if err == nil || err.Error() != tt.expectedError {
+					t.Fatalf("ExecCommand() error = %v, want %q", err, tt.expectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExecCommand() error = %v", err)
+			}
+			if strings.TrimSpace(output) != tt.expected {
+				t.Errorf("ran %q, want %q", strings.TrimSpace(output), tt.expected)
+			}
+		})
+	}
+
+	t.Run("suggestions without a terminal", func(t *testing.T) {
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			t.Skip("stdin is a terminal")
+		}
+
+		err := handler.ExecCommand(configPath, "", "pull", handler.ExecOptions{})
+		// git pull ran most recently, so it comes first.
+		want := "ambiguous command: pull is saved under 2 prefixes: did you mean git pull, gh pull?"
+		if err == nil || err.Error() != want {
+			t.Errorf("ExecCommand() error = %v, want %q", err, want)
+		}
+
+		err = handler.ExecCommand(configPath, "", "push-mian", handler.ExecOptions{})
+		want = "command not found: push-mian: did you mean git push-main?"
+		if err == nil || err.Error() != want {
+			t.Errorf("ExecCommand() error = %v, want %q", err, want)
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+)
+
+type RunOptions struct {
+	ExecOptions
+	// Parallel runs every command at once instead of one after another.
+	Parallel bool
+	// ContinueOnError keeps running the remaining commands after a failure
+	// instead of skipping (or, in parallel, cancelling) them.
+	ContinueOnError bool
+}
+
+type runStatus string
+
+const (
+	statusOK       runStatus = "ok"
+	statusFailed   runStatus = "failed"
+	statusSkipped  runStatus = "skipped"
+	statusCanceled runStatus = "canceled"
+)
+
+type runResult struct {
+	ref      domain.Ref
+	status   runStatus
+	err      error
+	duration time.Duration
+}
+
+// RunCommands runs several stored commands in sequence or in parallel and
+// prints a summary of their results. Every command is looked up and, when
+// high risk, confirmed before the first one starts.
+func RunCommands(configPath string, refs []domain.Ref, opts RunOptions) error {
+	if opts.From != "" && len(refs) > 1 {
+		return fmt.Errorf("--from can only be used with a single workflow")
+	}
+	if len(opts.Args) > 0 && len(refs) > 1 {
+		return fmt.Errorf("arguments can only be passed to a single command")
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	jobs := make([]execJob, len(refs))
+	for i, ref := range refs {
+		if ref, err = resolveRef(configPath, cfg, ref.Prefix, ref.Short, opts.ExecOptions); err != nil {
+			return err
+		}
+		if jobs[i], err = prepareJob(configPath, cfg, ref, opts.ExecOptions); err != nil {
+			return err
+		}
+	}
+
+	var results []runResult
+	if opts.Parallel {
+		results = runParallel(jobs, opts)
+	} else {
+		results = runSequence(jobs, opts)
+	}
+
+	return summarize(os.Stderr, results)
+}
+
+func runSequence(jobs []execJob, opts RunOptions) []runResult {
+	results := make([]runResult, len(jobs))
+	failed := false
+
+	for i, job := range jobs {
+		if failed && !opts.ContinueOnError {
+			results[i] = runResult{ref: job.ref, status: statusSkipped}
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "==> %s\n", job.ref)
+		start := time.Now()
+		err := job.run(context.Background(), os.Stdin, os.Stdout, os.Stderr, opts.ExecOptions)
+		results[i] = newRunResult(job.ref, err, time.Since(start))
+		failed = failed || err != nil
+	}
+	return results
+}
+
+// runParallel runs every job at once without stdin, prefixing each line of
+// output with the name of its entry. Unless ContinueOnError is set, the
+// first failure cancels the remaining jobs.
+func runParallel(jobs []execJob, opts RunOptions) []runResult {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := make([]runResult, len(jobs))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			stdout := newPrefixWriter(&mu, os.Stdout, job.ref.String())
+			stderr := newPrefixWriter(&mu, os.Stderr, job.ref.String())
+
+			job.policy.processGroup = true
+			start := time.Now()
+			err := job.run(ctx, nil, stdout, stderr, opts.ExecOptions)
+			stdout.Flush()
+			stderr.Flush()
+
+			results[i] = newRunResult(job.ref, err, time.Since(start))
+			if results[i].status == statusFailed && !opts.ContinueOnError {
+				cancel()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+func newRunResult(ref domain.Ref, err error, duration time.Duration) runResult {
+	result := runResult{ref: ref, status: statusOK, err: err, duration: duration}
+	switch {
+	case errors.Is(err, errInterrupted):
+		result.status = statusCanceled
+	case err != nil:
+		result.status = statusFailed
+	}
+	return result
+}
+
+func summarize(w io.Writer, results []runResult) error {
+	failed := 0
+	fmt.Fprintln(w, "Summary:")
+	for _, r := range results {
+		line := fmt.Sprintf("  %-8s %s", r.status, r.ref)
+		if r.status != statusSkipped {
+			line += fmt.Sprintf(" (%s)", r.duration.Round(time.Millisecond))
+		}
+		if r.status == statusFailed {
+			line += ": " + r.err.Error()
+			failed++
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d commands failed", failed, len(results))
+	}
+	for _, r := range results {
+		if r.status != statusOK {
+			return fmt.Errorf("%s was %s", r.ref, r.status)
+		}
+	}
+	return nil
+}
+
+// prefixWriter writes complete lines to w, each preceded by the name of the
+// entry producing them. Writers sharing mu never interleave within a line.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func newPrefixWriter(mu *sync.Mutex, w io.Writer, name string) *prefixWriter {
+	return &prefixWriter{mu: mu, w: w, prefix: "[" + name + "] "}
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(data), nil
+		}
+		if err := p.writeLine(p.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush writes a trailing partial line.
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		_ = p.writeLine(append(p.buf, '\n'))
+		p.buf = nil
+	}
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+	return err
+}
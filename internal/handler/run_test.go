@@ -0,0 +1,105 @@
+package handler_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+)
+
+func TestRunCommands(t *testing.T) {
+	tests := []struct {
+		name          string
+		refs          []string
+		opts          handler.RunOptions
+		expectedError string
+		wantMarker    bool
+		wantOutput    []string
+	}{
+		{
+			name:       "runs in sequence",
+			refs:       []string{"job:hello", "job:mark"},
+			wantMarker: true,
+			wantOutput: []string{"hello\n"},
+		},
+		{
+			name:          "stops at the first failure",
+			refs:          []string{"job:fail", "job:mark"},
+			expectedError: "1 of 2 commands failed",
+		},
+		{
+			name:          "continues after a failure",
+			refs:          []string{"job:fail", "job:mark"},
+			opts:          handler.RunOptions{ContinueOnError: true},
+			expectedError: "1 of 2 commands failed",
+			wantMarker:    true,
+		},
+		{
+			name:          "unknown entry fails before anything runs",
+			refs:          []string{"job:mark", "job:missing"},
+			expectedError: "command not found: job missing",
+		},
+		{
+			name:       "prefixes output in parallel",
+			refs:       []string{"job:hello", "job:mark"},
+			opts:       handler.RunOptions{Parallel: true},
+			wantMarker: true,
+			wantOutput: []string{"[job:hello] hello\n"},
+		},
+		{
+			name:          "failure cancels the other parallel commands",
+			refs:          []string{"job:sleep", "job:fail"},
+			opts:          handler.RunOptions{Parallel: true},
+			expectedError: "1 of 2 commands failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marker := t.TempDir() + "/marker"
+			configPath, err := createTempConfig(`
+[commands.job]
+hello = "echo hello"
+mark = "touch ` + marker + `"
+fail = "exit 1"
+sleep = "sleep 5"
+`)
+			if err != nil {
+				t.Fatalf("failed to create temp config file: %v", err)
+			}
+			defer cleanupTempFile(configPath)
+
+			var refs []domain.Ref
+			for _, s := range tt.refs {
+				ref, err := domain.ParseRef(s)
+				if err != nil {
+					t.Fatalf("ParseRef(%q) error = %v", s, err)
+				}
+				refs = append(refs, ref)
+			}
+
+			start := time.Now()
+			output := captureStdout(t, func() {
+				err = handler.RunCommands(configPath, refs, tt.opts)
+			})
+
+			if (err != nil && err.Error() != tt.expectedError) || (err == nil && tt.expectedError != "") {
+				t.Errorf("unexpected error: got %v, want %v", err, tt.expectedError)
+			}
+			if _, statErr := os.Stat(marker); (statErr == nil) != tt.wantMarker {
+				t.Errorf("marker exists = %v, want %v", statErr == nil, tt.wantMarker)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(output, want) {
+					t.Errorf("output missing %q:\n%s", want, output)
+				}
+			}
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Errorf("took %s; remaining commands were not cancelled", elapsed)
+			}
+		})
+	}
+}
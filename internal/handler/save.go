@@ -0,0 +1,13 @@
+package handler
+
+import (
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/gitsync"
+)
+
+func saveConfig(cfg *config.Config, configPath, message string) error {
+	if err := config.SaveConfig(cfg, configPath); err != nil {
+		return err
+	}
+	return gitsync.Commit(configPath, message)
+}
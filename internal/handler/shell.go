@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+)
+
+const defaultShell = "sh"
+
+// inlineFlags introduce inline code for common shells and interpreters
+// ("sh -c", "python3 -c", "node -e"). They are dropped when a multi-line
+// command is run from a script file instead.
+var inlineFlags = map[string]bool{"-c": true, "-e": true}
+
+// entryShell returns the shell of entry, falling back to the configured
+// default and then to sh.
+func entryShell(cfg *config.Config, entry config.Entry) string {
+	if entry.Meta.Shell != "" {
+		return entry.Meta.Shell
+	}
+	if cfg.Settings.Shell != "" {
+		return cfg.Settings.Shell
+	}
+	return defaultShell
+}
+
+// posixShells run "sh -c" commands with the arguments after the command as
+// positional parameters, which lets extra arguments be appended as "$@".
+var posixShells = map[string]bool{"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true}
+
+// shellCommand prepares the process running command with shell. A shell
+// given as a single word gets -c appended; one given with flags, such as
+// "python3 -c", is used as is. Multi-line commands are written to a
+// temporary script; the returned cleanup removes it.
+//
+// extra is appended to single-line commands run by a POSIX shell, unless
+// the command places it with "$@"; scripts and other interpreters receive
+// it as their arguments.
+func shellCommand(ctx context.Context, shell, command string, extra []string) (*exec.Cmd, func(), error) {
+	args := strings.Fields(shell)
+	if len(args) == 0 {
+		args = []string{defaultShell}
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil, nil, fmt.Errorf("shell not found: %s", args[0])
+	}
+
+	if !strings.Contains(command, "\n") {
+		if len(args) == 1 {
+			args = append(args, "-c")
+		}
+		if len(extra) > 0 && posixShells[filepath.Base(args[0])] && args[len(args)-1] == "-c" {
+			if !domain.RefersToArgs(command) {
+				if !domain.AcceptsAppendedArgs(command) {
+					return nil, nil, fmt.Errorf(`arguments cannot be appended to a command ending in an operator or comment; place them with "$@"`)
+				}
+				command += ` "$@"`
+			}
+			// The first argument after the command becomes $0.
+			extra = append([]string{args[0]}, extra...)
+		}
+		return exec.CommandContext(ctx, args[0], append(append(args[1:], command), extra...)...), func() {}, nil
+	}
+
+	if inlineFlags[args[len(args)-1]] {
+		args = args[:len(args)-1]
+	}
+
+	script, err := writeScript(command)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { _ = os.Remove(script) }
+
+	return exec.CommandContext(ctx, args[0], append(append(args[1:], script), extra...)...), cleanup, nil
+}
+
+func writeScript(command string) (string, error) {
+	file, err := os.CreateTemp("", "cmdbook-script-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create script: %w", err)
+	}
+	defer file.Close()
+
+	if !strings.HasSuffix(command, "\n") {
+		command += "\n"
+	}
+	if _, err := file.WriteString(command); err != nil {
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write script: %w", err)
+	}
+	return file.Name(), nil
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// shellInits bind Ctrl-G to cb pick and insert the chosen command at the
+// cursor, leaving it to be edited and run by the user.
+var shellInits = map[string]string{
+	"bash": `# cmdbook: Ctrl-G inserts a saved command at the cursor.
+_cb_pick_widget() {
+  local selected
+  selected="$(cb pick)" || return
+  READLINE_LINE="${READLINE_LINE:0:READLINE_POINT}${selected}${READLINE_LINE:READLINE_POINT}"
+  READLINE_POINT=$((READLINE_POINT + ${#selected}))
+}
+bind -x '"\C-g": _cb_pick_widget'
+`,
+	"zsh": `# cmdbook: Ctrl-G inserts a saved command at the cursor.
+_cb_pick_widget() {
+  local selected
+  selected="$(cb pick)"
+  if [[ -n "$selected" ]]; then
+    LBUFFER+="$selected"
+  fi
+  zle reset-prompt
+}
+zle -N _cb_pick_widget
+bindkey '^G' _cb_pick_widget
+`,
+	"fish": `# cmdbook: Ctrl-G inserts a saved command at the cursor.
+function _cb_pick_widget
+    set -l selected (cb pick | string collect)
+    if test -n "$selected"
+        commandline -i -- $selected
+    end
+    commandline -f repaint
+end
+bind \cg _cb_pick_widget
+`,
+}
+
+// ShellInit prints the integration code for shell, to be evaluated from its
+// startup file.
+func ShellInit(shell string) error {
+	script, ok := shellInits[shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell: %s (use %s)", shell, strings.Join(SupportedShells(), ", "))
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// SupportedShells lists the shells cb init can integrate with.
+func SupportedShells() []string {
+	shells := make([]string, 0, len(shellInits))
+	for shell := range shellInits {
+		shells = append(shells, shell)
+	}
+	sort.Strings(shells)
+	return shells
+}
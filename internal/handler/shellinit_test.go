@@ -0,0 +1,41 @@
+package handler_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+)
+
+func TestShellInit(t *testing.T) {
+	tests := []struct {
+		shell         string
+		binding       string
+		expectedError string
+	}{
+		{shell: "bash", binding: `bind -x '"\C-g": _cb_pick_widget'`},
+		{shell: "zsh", binding: "bindkey '^G' _cb_pick_widget"},
+		{shell: "fish", binding: `bind \cg _cb_pick_widget`},
+		{shell: "tcsh", expectedError: "unsupported shell: tcsh (use bash, fish, zsh)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			var err error
+			output := captureStdout(t, func() { err = handler.ShellInit(tt.shell) })
+
+			if tt.expectedError != "" {
+				if err == nil || err.Error() != tt.expectedError {
+					t.Errorf("ShellInit() error = %v, want %q", err, tt.expectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ShellInit() error = %v", err)
+			}
+			if !strings.Contains(output, "cb pick") || !strings.Contains(output, tt.binding) {
+				t.Errorf("unexpected integration code:\n%s", output)
+			}
+		})
+	}
+}
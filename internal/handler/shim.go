@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/shim"
+)
+
+// ShimInstall writes a shim for every entry of the book into dir and
+// remembers dir for ShimSync. Shims left in a previously used directory are
+// removed.
+func ShimInstall(configPath, dir string) error {
+	dir, err := expandPath(dir)
+	if err != nil {
+		return err
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return err
+	}
+
+	previous, err := shim.LoadState(configPath)
+	if err != nil && !errors.Is(err, shim.ErrNotInstalled) {
+		return err
+	}
+	if previous.Dir != "" && previous.Dir != dir {
+		if err := syncShims(configPath, previous.Dir, nil); err != nil {
+			return err
+		}
+	}
+
+	if err := shim.SaveState(configPath, shim.State{Dir: dir}); err != nil {
+		return fmt.Errorf("failed to save shim directory: %w", err)
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := syncShims(configPath, dir, bookRefs(cfg)); err != nil {
+		return err
+	}
+
+	if !inPath(dir) {
+		fmt.Printf("Note: %s is not in PATH\n", dir)
+	}
+	return nil
+}
+
+// ShimSync brings the installed shims in line with the book, removing those
+// of renamed and removed entries.
+func ShimSync(configPath string) error {
+	state, err := shim.LoadState(configPath)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return syncShims(configPath, state.Dir, bookRefs(cfg))
+}
+
+func syncShims(configPath, dir string, refs []domain.Ref) error {
+	cb, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate cb: %w", err)
+	}
+
+	result, err := shim.Sync(dir, cb, refs)
+	printShimResult(dir, result)
+	return err
+}
+
+func printShimResult(dir string, result shim.Result) {
+	for _, name := range result.Created {
+		fmt.Printf("Created: %s\n", filepath.Join(dir, name))
+	}
+	for _, name := range result.Updated {
+		fmt.Printf("Updated: %s\n", filepath.Join(dir, name))
+	}
+	for _, name := range result.Removed {
+		fmt.Printf("Removed: %s\n", filepath.Join(dir, name))
+	}
+
+	skipped := make([]string, 0, len(result.Skipped))
+	for entry := range result.Skipped {
+		skipped = append(skipped, entry)
+	}
+	sort.Strings(skipped)
+	for _, entry := range skipped {
+		fmt.Printf("Skipped: %s (%s)\n", entry, result.Skipped[entry])
+	}
+
+	if len(result.Created)+len(result.Updated)+len(result.Removed)+len(skipped) == 0 {
+		fmt.Printf("Shims in %s are up to date\n", dir)
+	}
+}
+
+// bookRefs lists the entries of the book itself, leaving out remote ones.
+func bookRefs(cfg *config.Config) []domain.Ref {
+	var refs []domain.Ref
+	for prefix, cmds := range cfg.Commands {
+		for short := range cmds {
+			refs = append(refs, domain.Ref{Prefix: prefix, Short: short})
+		}
+	}
+	return refs
+}
+
+func inPath(dir string) bool {
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		if filepath.Clean(entry) == dir {
+			return true
+		}
+	}
+	return false
+}
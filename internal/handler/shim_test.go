@@ -0,0 +1,61 @@
+package handler_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+)
+
+func TestShims(t *testing.T) {
+	configPath, err := createTempConfig(`
+[commands.git]
+push-main = "git push origin main"
+st = "git status"
+`)
+	if err != nil {
+		t.Fatalf("failed to create temp config file: %v", err)
+	}
+	defer cleanupTempFile(configPath)
+
+	if err := handler.ShimSync(configPath); err == nil {
+		t.Error("ShimSync() before install should fail")
+	}
+
+	dir := filepath.Join(t.TempDir(), "bin")
+	captureStdout(t, func() {
+		if err := handler.ShimInstall(configPath, dir); err != nil {
+			t.Fatalf("ShimInstall() error = %v", err)
+		}
+	})
+	for _, name := range []string{"git-push-main", "git-st"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.Mode()&0o111 == 0 {
+			t.Errorf("%s is not an executable shim: %v", name, err)
+		}
+	}
+
+	captureStdout(t, func() {
+		_ = handler.RemoveCommand(configPath, "git", "st")
+		if err := handler.ShimSync(configPath); err != nil {
+			t.Fatalf("ShimSync() error = %v", err)
+		}
+	})
+	if _, err := os.Stat(filepath.Join(dir, "git-st")); !os.IsNotExist(err) {
+		t.Errorf("the shim of a removed entry should be gone: %v", err)
+	}
+
+	// Moving the shims leaves nothing behind in the old directory.
+	moved := filepath.Join(t.TempDir(), "bin")
+	captureStdout(t, func() {
+		if err := handler.ShimInstall(configPath, moved); err != nil {
+			t.Fatalf("ShimInstall() error = %v", err)
+		}
+	})
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("shims left in the old directory: %v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(moved, "git-push-main")); err != nil {
+		t.Errorf("shim missing from the new directory: %v", err)
+	}
+}
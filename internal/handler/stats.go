@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/history"
+	"github.com/pHo9UBenaA/cmdbook/internal/remote"
+	"github.com/pHo9UBenaA/cmdbook/pkg/ioutil"
+)
+
+// bookUsage pairs the entries of a book with how they have been used.
+type bookUsage struct {
+	book   *config.Config
+	refs   []domain.Ref
+	usages map[domain.Ref]history.Usage
+	// since is the time of the oldest recorded run.
+	since time.Time
+}
+
+func loadBookUsage(configPath string, book *config.Config) (bookUsage, error) {
+	records, err := history.Load(configPath, history.Filter{})
+	if err != nil {
+		return bookUsage{}, fmt.Errorf("failed to load history: %w", err)
+	}
+
+	u := bookUsage{book: book, usages: history.Usages(records, time.Now())}
+	if len(records) > 0 {
+		u.since = records[0].Time
+	}
+	for prefix, cmds := range book.Commands {
+		for short := range cmds {
+			u.refs = append(u.refs, domain.Ref{Prefix: prefix, Short: short})
+		}
+	}
+	history.Rank(u.refs, nil)
+	return u, nil
+}
+
+func (u bookUsage) command(ref domain.Ref) string {
+	return ioutil.FirstLine(u.book.Commands[ref.Prefix][ref.Short])
+}
+
+// Stats prints the most used, the most recently used and the never used
+// entries, at most limit of each.
+func Stats(configPath string, limit int) error {
+	u, err := loadExecUsage(configPath)
+	if err != nil {
+		return err
+	}
+	if len(u.refs) == 0 {
+		fmt.Println("No commands saved")
+		return nil
+	}
+
+	var used, never []domain.Ref
+	for _, ref := range u.refs {
+		if _, ok := u.usages[ref]; ok {
+			used = append(used, ref)
+		} else {
+			never = append(never, ref)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	sort.SliceStable(used, func(i, j int) bool { return u.usages[used[i]].Count > u.usages[used[j]].Count })
+	fmt.Fprintln(w, "Most used:")
+	u.printUsed(w, truncateRefs(used, limit))
+
+	sort.SliceStable(used, func(i, j int) bool {
+		return u.usages[used[i]].LastUsed.After(u.usages[used[j]].LastUsed)
+	})
+	fmt.Fprintln(w, "\nRecently used:")
+	u.printUsed(w, truncateRefs(used, limit))
+
+	fmt.Fprintln(w, "\nNever used:")
+	if len(never) == 0 {
+		fmt.Fprintln(w, "  (none)")
+	}
+	for _, ref := range truncateRefs(never, limit) {
+		fmt.Fprintf(w, "  %s %s\t%s\n", ref.Prefix, ref.Short, u.command(ref))
+	}
+	if len(never) > limit && limit > 0 {
+		fmt.Fprintf(w, "  ... and %d more\n", len(never)-limit)
+	}
+
+	return w.Flush()
+}
+
+func (u bookUsage) printUsed(w *tabwriter.Writer, refs []domain.Ref) {
+	if len(refs) == 0 {
+		fmt.Fprintln(w, "  (none)")
+	}
+	for _, ref := range refs {
+		usage := u.usages[ref]
+		runs := "runs"
+		if usage.Count == 1 {
+			runs = "run"
+		}
+		fmt.Fprintf(w, "  %s %s\t%d %s\t%d failed\tlast %s ago\t%s\n",
+			ref.Prefix, ref.Short, usage.Count, runs, usage.Failures, formatAge(time.Since(usage.LastUsed)), u.command(ref))
+	}
+}
+
+// Top prints the entries ranked by frecency, at most limit of them.
+func Top(configPath string, limit int) error {
+	u, err := loadExecUsage(configPath)
+	if err != nil {
+		return err
+	}
+
+	var used []domain.Ref
+	scores := make(map[domain.Ref]float64)
+	for _, ref := range u.refs {
+		if usage, ok := u.usages[ref]; ok {
+			used = append(used, ref)
+			scores[ref] = usage.Score
+		}
+	}
+	if len(used) == 0 {
+		fmt.Println("No runs recorded")
+		return nil
+	}
+	history.Rank(used, scores)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SCORE\tENTRY\tRUNS\tLAST USED\tCOMMAND")
+	for _, ref := range truncateRefs(used, limit) {
+		usage := u.usages[ref]
+		fmt.Fprintf(w, "%.2f\t%s %s\t%d\t%s ago\t%s\n",
+			usage.Score, ref.Prefix, ref.Short, usage.Count, formatAge(time.Since(usage.LastUsed)), u.command(ref))
+	}
+	return w.Flush()
+}
+
+// Prune lists the entries of the book that have not run within unusedFor
+// as candidates for removal. Nothing is removed.
+func Prune(configPath, unusedFor string) error {
+	age, err := history.ParseAge(unusedFor)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	u, err := loadBookUsage(configPath, cfg)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-age)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	found := 0
+	for _, ref := range u.refs {
+		usage, ok := u.usages[ref]
+		switch {
+		case !ok:
+			fmt.Fprintf(w, "  %s %s\tnever used\t%s\n", ref.Prefix, ref.Short, u.command(ref))
+		case usage.LastUsed.Before(cutoff):
+			fmt.Fprintf(w, "  %s %s\tlast used %s\t%s\n", ref.Prefix, ref.Short, usage.LastUsed.Local().Format("2006-01-02"), u.command(ref))
+		default:
+			continue
+		}
+		found++
+	}
+
+	if found == 0 {
+		fmt.Printf("No entries unused for %s\n", unusedFor)
+		return nil
+	}
+
+	fmt.Printf("Unused for %s (suggestions only, nothing was removed):\n", unusedFor)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Println("Remove with: cb remove <prefix> <short>")
+	if u.since.IsZero() || u.since.After(cutoff) {
+		fmt.Println("Note: the history is younger than that; recently added entries may show as never used.")
+	}
+	return nil
+}
+
+// loadExecUsage covers every entry cb exec can run, including remote ones.
+func loadExecUsage(configPath string) (bookUsage, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return bookUsage{}, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	view, err := remote.WithRemotes(configPath, cfg)
+	if err != nil {
+		return bookUsage{}, err
+	}
+	return loadBookUsage(configPath, view)
+}
+
+func truncateRefs(refs []domain.Ref, limit int) []domain.Ref {
+	if limit > 0 && len(refs) > limit {
+		return refs[:limit]
+	}
+	return refs
+}
+
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
@@ -0,0 +1,94 @@
+package handler_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+	"github.com/pHo9UBenaA/cmdbook/internal/history"
+)
+
+func TestStatsTopPrune(t *testing.T) {
+	configPath, err := createTempConfig(`
+[commands.git]
+st = "git status"
+push = "git push"
+legacy = "git svn rebase"
+
+[commands.docker]
+up = "docker compose up"
+`)
+	if err != nil {
+		t.Fatalf("failed to create temp config file: %v", err)
+	}
+	defer cleanupTempFile(configPath)
+
+	now := time.Now()
+	day := 24 * time.Hour
+	for _, r := range []history.Record{
+		{Prefix: "git", Short: "legacy", Time: now.Add(-400 * day)},
+		{Prefix: "git", Short: "st", Time: now.Add(-30 * day)},
+		{Prefix: "git", Short: "st", Time: now.Add(-29 * day)},
+		{Prefix: "git", Short: "st", Time: now.Add(-28 * day), ExitCode: 1},
+		{Prefix: "git", Short: "push", Time: now.Add(-time.Hour)},
+		{Prefix: "gone", Short: "old", Time: now},
+	} {
+		if err := history.Append(configPath, r); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		run   func() error
+		want  []string
+		order []string
+	}{
+		{
+			name:  "stats",
+			run:   func() error { return handler.Stats(configPath, 10) },
+			want:  []string{"Most used:", "3 runs  1 failed", "1 run ", "Recently used:", "Never used:", "docker up"},
+			order: []string{"Most used:", "git st", "Recently used:", "git push", "Never used:", "docker up"},
+		},
+		{
+			name:  "top",
+			run:   func() error { return handler.Top(configPath, 10) },
+			want:  []string{"SCORE", "git push", "git st", "git legacy"},
+			order: []string{"git push", "git st", "git legacy"},
+		},
+		{
+			name: "prune",
+			run:  func() error { return handler.Prune(configPath, "180d") },
+			want: []string{"suggestions only", "git legacy  last used", "docker up   never used", "cb remove"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := captureStdout(t, func() { err = tt.run() })
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("output missing %q:\n%s", want, output)
+				}
+			}
+			if strings.Contains(output, "gone") {
+				t.Errorf("removed entries must not be listed:\n%s", output)
+			}
+
+			last := -1
+			for _, want := range tt.order {
+				i := strings.Index(output[last+1:], want)
+				if i < 0 {
+					t.Errorf("%q out of order:\n%s", want, output)
+					break
+				}
+				last += i + 1
+			}
+		})
+	}
+}
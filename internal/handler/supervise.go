@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+)
+
+const retryOnTimeout = "timeout"
+
+var errInterrupted = errors.New("interrupted")
+
+type runPolicy struct {
+	timeout    time.Duration
+	retries    int
+	retryDelay time.Duration
+	retryOn    []string
+	// processGroup runs each attempt in its own process group, which is
+	// killed as a whole when the attempt is cancelled.
+	processGroup bool
+}
+
+type attempt struct {
+	err         error
+	timedOut    bool
+	interrupted bool
+	duration    time.Duration
+}
+
+// resolvePolicy combines the timeout and retry settings of an entry with
+// the flags given to exec, which take precedence.
+func resolvePolicy(meta config.EntryMeta, opts ExecOptions) (runPolicy, error) {
+	policy := runPolicy{retries: meta.Retry, retryOn: meta.RetryOn}
+
+	var err error
+	if policy.timeout, err = parseDuration("timeout", meta.Timeout); err != nil {
+		return runPolicy{}, err
+	}
+	if policy.retryDelay, err = parseDuration("retry delay", meta.RetryDelay); err != nil {
+		return runPolicy{}, err
+	}
+
+	if opts.Timeout != 0 {
+		policy.timeout = opts.Timeout
+	}
+	if opts.Retry != 0 {
+		policy.retries = opts.Retry
+	}
+	if opts.RetryDelay != 0 {
+		policy.retryDelay = opts.RetryDelay
+	}
+	if len(opts.RetryOn) > 0 {
+		policy.retryOn = opts.RetryOn
+	}
+
+	if policy.timeout < 0 || policy.retries < 0 || policy.retryDelay < 0 {
+		return runPolicy{}, fmt.Errorf("timeout, retry and retry delay cannot be negative")
+	}
+	for _, cond := range policy.retryOn {
+		if _, err := strconv.Atoi(cond); err != nil && cond != retryOnTimeout {
+			return runPolicy{}, fmt.Errorf("invalid retry condition '%s': use exit codes or %s", cond, retryOnTimeout)
+		}
+	}
+
+	policy.processGroup = policy.timeout > 0
+	return policy, nil
+}
+
+func parseDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s '%s': %w", name, value, err)
+	}
+	return d, nil
+}
+
+// supervise runs the process built by build until it succeeds, the retry
+// policy gives up or ctx is cancelled. A summary of the attempts is written
+// to summary when the command was retried or timed out.
+func supervise(ctx context.Context, policy runPolicy, summary io.Writer, build func(ctx context.Context) (*exec.Cmd, func(), error)) error {
+	var attempts []attempt
+	for {
+		a, err := runAttempt(ctx, policy, build)
+		if err != nil {
+			return err
+		}
+		attempts = append(attempts, a)
+
+		if a.err == nil || len(attempts) > policy.retries || !policy.shouldRetry(a) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return errInterrupted
+		case <-time.After(policy.retryDelay):
+		}
+	}
+
+	last := attempts[len(attempts)-1]
+	if len(attempts) > 1 || last.timedOut {
+		printAttempts(summary, attempts)
+	}
+	if last.err != nil && len(attempts) > 1 {
+		return fmt.Errorf("failed after %d attempts: %w", len(attempts), last.err)
+	}
+	return last.err
+}
+
+// runAttempt runs one attempt. The returned error is set only when the
+// process could not be started, which retrying would not fix.
+func runAttempt(ctx context.Context, policy runPolicy, build func(ctx context.Context) (*exec.Cmd, func(), error)) (attempt, error) {
+	if policy.processGroup {
+		// Unless it is handed the terminal, the process no longer receives
+		// signals from it, so interrupting cb has to cancel it.
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+	}
+	if policy.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.timeout)
+		defer cancel()
+	}
+
+	execCmd, cleanup, err := build(ctx)
+	if err != nil {
+		return attempt{}, err
+	}
+	defer cleanup()
+
+	if policy.processGroup {
+		restore := setProcessGroup(execCmd)
+		defer restore()
+	}
+
+	start := time.Now()
+	if err := execCmd.Start(); err != nil {
+		return attempt{}, err
+	}
+	a := attempt{err: execCmd.Wait()}
+	a.duration = time.Since(start)
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		a.timedOut = true
+		a.err = fmt.Errorf("timed out after %s", policy.timeout)
+	case ctx.Err() != nil || interruptedByUser(a.err):
+		a.interrupted = true
+		a.err = errInterrupted
+	}
+	return a, nil
+}
+
+// interruptedByUser reports whether err is the exit of a process killed by
+// SIGINT, which a command holding the terminal receives instead of cb.
+func interruptedByUser(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGINT
+}
+
+func (p runPolicy) shouldRetry(a attempt) bool {
+	if a.interrupted {
+		return false
+	}
+	if len(p.retryOn) == 0 {
+		return true
+	}
+
+	code := ""
+	var exitErr *exec.ExitError
+	if a.timedOut {
+		code = retryOnTimeout
+	} else if errors.As(a.err, &exitErr) {
+		code = strconv.Itoa(exitErr.ExitCode())
+	}
+
+	for _, cond := range p.retryOn {
+		if cond == code {
+			return true
+		}
+	}
+	return false
+}
+
+func printAttempts(w io.Writer, attempts []attempt) {
+	fmt.Fprintf(w, "Attempts: %d\n", len(attempts))
+	for i, a := range attempts {
+		result := "succeeded"
+		if a.err != nil {
+			result = a.err.Error()
+		}
+		fmt.Fprintf(w, "  #%d %s (%s)\n", i+1, result, a.duration.Round(time.Millisecond))
+	}
+}
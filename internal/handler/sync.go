@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/gitsync"
+)
+
+func SyncInit(configPath, target, remote string) error {
+	repoDir, err := gitsync.Init(configPath, target, remote, mergeDriverCommand())
+	if err != nil {
+		return fmt.Errorf("failed to initialize sync: %w", err)
+	}
+
+	fmt.Printf("Synced: %s -> %s\n", configPath, repoDir)
+	return nil
+}
+
+func SyncPull(configPath string) error {
+	if err := gitsync.Pull(configPath); err != nil {
+		return fmt.Errorf("failed to pull: %w", err)
+	}
+
+	fmt.Println("Pulled command book")
+	return nil
+}
+
+func SyncPush(configPath string) error {
+	if err := gitsync.Push(configPath); err != nil {
+		return fmt.Errorf("failed to push: %w", err)
+	}
+
+	fmt.Println("Pushed command book")
+	return nil
+}
+
+// mergeDriverCommand returns the shell command git runs to merge the book,
+// or "" when the cb executable cannot be located.
+func mergeDriverCommand() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	return "'" + strings.ReplaceAll(exe, "'", `'\''`) + "' merge"
+}
@@ -0,0 +1,47 @@
+package handler_test
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+)
+
+func TestSyncCommitsChanges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	repoDir := filepath.Join(t.TempDir(), "repo")
+
+	if err := handler.SyncInit(configPath, repoDir, ""); err != nil {
+		t.Fatalf("SyncInit() error = %v", err)
+	}
+	if err := handler.AddCommand(configPath, "git", "push-main", "git push origin main", handler.AddOptions{}); err != nil {
+		t.Fatalf("AddCommand() error = %v", err)
+	}
+	if err := handler.UpdateCommand(configPath, "git", "push-main", "", "pm", "", handler.UpdateOptions{}); err != nil {
+		t.Fatalf("UpdateCommand() error = %v", err)
+	}
+	if err := handler.RemoveCommand(configPath, "git", "pm"); err != nil {
+		t.Fatalf("RemoveCommand() error = %v", err)
+	}
+
+	out, err := exec.Command("git", "-C", repoDir, "log", "--format=%s").Output()
+	if err != nil {
+		t.Fatalf("git log failed: %v", err)
+	}
+
+	want := []string{
+		"remove git pm",
+		"update git push-main -> git pm",
+		"add git push-main",
+		"sync init",
+	}
+	if got := strings.Split(strings.TrimSpace(string(out)), "\n"); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("commit messages = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/history"
+)
+
+// parseSet converts the NAME=VALUE assignments given with --set into a map.
+func parseSet(assignments []string) (map[string]string, error) {
+	values := make(map[string]string, len(assignments))
+	for _, assignment := range assignments {
+		name, value, ok := strings.Cut(assignment, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --set value '%s': use NAME=VALUE", assignment)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// renderCommand fills the template variables of command, the command of
+// ref, with the values given in opts.Set. It also returns the values that
+// were used, for the history.
+func renderCommand(ref domain.Ref, command string, opts ExecOptions) (string, map[string]string, error) {
+	values, err := parseSet(opts.Set)
+	if err != nil {
+		return "", nil, err
+	}
+
+	names := domain.TemplateVars(command)
+	if len(names) == 0 {
+		return command, nil, nil
+	}
+
+	rendered, err := domain.RenderTemplate(command, values)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w in %s %s: pass --set NAME=VALUE", err, ref.Prefix, ref.Short)
+	}
+
+	used := make(map[string]string, len(names))
+	for _, name := range names {
+		used[name] = values[name]
+	}
+	return rendered, used, nil
+}
+
+// TemplateVars returns the template variables of the entries named by
+// refs, including those run as steps of workflows, along with the values
+// each was given in earlier runs, most recent first.
+func TemplateVars(configPath string, refs []domain.Ref) ([]string, map[string][]string) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, nil
+	}
+
+	var names []string
+	seen := make(map[domain.Ref]bool)
+	var visit func(ref domain.Ref)
+	visit = func(ref domain.Ref) {
+		if seen[ref] {
+			return
+		}
+		seen[ref] = true
+
+		entry, err := lookupCommand(configPath, cfg, ref.Prefix, ref.Short)
+		if err != nil {
+			return
+		}
+		if entry.IsWorkflow() {
+			for _, step := range entry.Meta.Steps {
+				if next, err := stepRef(cfg, ref, step); err == nil {
+					visit(next)
+				}
+			}
+			return
+		}
+		for _, name := range domain.TemplateVars(entry.Command) {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	for _, ref := range refs {
+		visit(ref)
+	}
+
+	records, _ := history.Load(configPath, history.Filter{})
+	values := make(map[string][]string)
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		if !seen[domain.Ref{Prefix: r.Prefix, Short: r.Short}] {
+			continue
+		}
+		for name, value := range r.Set {
+			if slices.Contains(names, name) && !slices.Contains(values[name], value) {
+				values[name] = append(values[name], value)
+			}
+		}
+	}
+	return names, values
+}
@@ -4,13 +4,53 @@ import (
 	"fmt"
 
 	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
 )
 
-func UpdateCommand(configPath string, oldPrefix, oldShort, newPrefix, newShort, newCommand string) error {
-	if newPrefix == "" && newShort == "" && newCommand == "" {
+type UpdateOptions struct {
+	// Dir replaces the working directory when set; an empty value clears it.
+	Dir *string
+	// Env holds KEY=VALUE assignments to add or replace.
+	Env []string
+	// UnsetEnv lists environment variables to remove.
+	UnsetEnv []string
+	// Shell replaces the shell when set; an empty value restores the default.
+	Shell *string
+	// Steps replaces the steps of a workflow.
+	Steps []string
+	// Force replaces an existing entry at the new prefix and short.
+	Force bool
+	// Swap exchanges the entry with the one at the new prefix and short.
+	Swap bool
+}
+
+func (o UpdateOptions) isEmpty() bool {
+	return o.Dir == nil && len(o.Env) == 0 && len(o.UnsetEnv) == 0 && o.Shell == nil && len(o.Steps) == 0
+}
+
+// UpdateCommand changes an entry and moves it to newPrefix and newShort
+// when they are given. Everything is checked before the book is changed,
+// so a failing update leaves it as it was. An entry already at the new
+// name is only replaced with opts.Force, or exchanged with opts.Swap. The
+// history of a renamed entry follows it.
+func UpdateCommand(configPath string, oldPrefix, oldShort, newPrefix, newShort, newCommand string, opts UpdateOptions) error {
+	if newPrefix == "" && newShort == "" && newCommand == "" && opts.isEmpty() {
 		fmt.Println("No updates specified. Skipping command update.")
 		return nil
 	}
+	if opts.Force && opts.Swap {
+		return fmt.Errorf("--force and --swap cannot be used together")
+	}
+
+	env, err := parseEnv(opts.Env)
+	if err != nil {
+		return err
+	}
+
+	steps, err := parseSteps(opts.Steps)
+	if err != nil {
+		return err
+	}
 
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
@@ -22,79 +62,119 @@ func UpdateCommand(configPath string, oldPrefix, oldShort, newPrefix, newShort,
 		return fmt.Errorf("prefix not found: %s", oldPrefix)
 	}
 
-	originalCmd, ok := cmds[oldShort]
-	if !ok {
+	if _, ok := cmds[oldShort]; !ok {
 		return fmt.Errorf("command not found: %s %s", oldPrefix, oldShort)
 	}
 
-	if newPrefix == "" {
-		newPrefix = oldPrefix
-	} else {
-		err := updatePrefix(cfg, oldPrefix, oldShort, newPrefix, originalCmd)
+	entry, _ := cfg.GetEntry(oldPrefix, oldShort)
+	if newCommand != "" && (entry.IsWorkflow() || len(steps) > 0) {
+		return fmt.Errorf("a workflow takes steps instead of a command")
+	}
+	if len(steps) > 0 && !entry.IsWorkflow() {
+		return fmt.Errorf("%s %s is a command, not a workflow", oldPrefix, oldShort)
+	}
 
-		if err != nil {
+	policy := namePolicy(cfg)
+	if newPrefix != "" {
+		if err := policy.ValidatePrefix(newPrefix); err != nil {
 			return err
 		}
-	}
-
-	if newShort == "" {
-		newShort = oldShort
 	} else {
-		err := updateShort(cfg, newPrefix, oldShort, newShort, originalCmd)
-
-		if err != nil {
+		newPrefix = oldPrefix
+	}
+	if newShort != "" {
+		if err := policy.ValidateShort(newShort); err != nil {
 			return err
 		}
+	} else {
+		newShort = oldShort
 	}
 
-	if err := updateCommand(cfg, newPrefix, newShort, newCommand); err != nil {
-		return err
+	from := domain.Ref{Prefix: oldPrefix, Short: oldShort}
+	to := domain.Ref{Prefix: newPrefix, Short: newShort}
+	target, occupied := cfg.GetEntry(newPrefix, newShort)
+	if to == from {
+		occupied = false
 	}
-
-	if err := config.SaveConfig(cfg, configPath); err != nil {
-		return fmt.Errorf("failed to save configuration: %w", err)
+	switch {
+	case occupied && !opts.Force && !opts.Swap:
+		return fmt.Errorf("short already exists: %s %s (use --force to replace it or --swap to exchange the two)", newPrefix, newShort)
+	case opts.Swap && !occupied:
+		return fmt.Errorf("nothing to swap with: %s %s does not exist", newPrefix, newShort)
 	}
 
-	fmt.Printf("Updated: %s %s -> %s %s\n", oldPrefix, oldShort, newPrefix, newShort)
-	return nil
-}
-
-func updatePrefix(cfg *config.Config, oldPrefix, oldShort, newPrefix, originalCmd string) error {
-	cmds := cfg.Commands[oldPrefix]
-	delete(cmds, oldShort)
+	entry = updatedEntry(entry, newCommand, opts, env, steps)
 
-	if len(cmds) == 0 {
-		delete(cfg.Commands, oldPrefix)
+	// Every check has passed; apply the change as a whole.
+	cfg.DeleteEntry(oldPrefix, oldShort)
+	cfg.SetEntry(newPrefix, newShort, entry)
+	moved := map[string]string{from.String(): to.String()}
+	if opts.Swap {
+		cfg.SetEntry(oldPrefix, oldShort, target)
+		moved[to.String()] = from.String()
+	}
+	if to != from {
+		retargetSteps(cfg, moved)
 	}
 
-	if cfg.Commands[newPrefix] == nil {
-		cfg.Commands[newPrefix] = make(map[string]string)
+	message := fmt.Sprintf("update %s %s -> %s %s", oldPrefix, oldShort, newPrefix, newShort)
+	if opts.Swap {
+		message = fmt.Sprintf("swap %s %s <-> %s %s", oldPrefix, oldShort, newPrefix, newShort)
+	}
+	if err := saveConfig(cfg, configPath, message); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
 	}
 
-	cfg.Commands[newPrefix][oldShort] = originalCmd
+	if to != from {
+		renamed := map[domain.Ref]domain.Ref{from: to}
+		if opts.Swap {
+			renamed[to] = from
+		}
+		renameHistory(configPath, renamed)
+	}
 
+	if opts.Swap {
+		fmt.Printf("Swapped: %s %s <-> %s %s\n", oldPrefix, oldShort, newPrefix, newShort)
+	} else {
+		fmt.Printf("Updated: %s %s -> %s %s\n", oldPrefix, oldShort, newPrefix, newShort)
+	}
 	return nil
 }
 
-func updateShort(cfg *config.Config, prefix, oldShort, newShort, originalCmd string) error {
-	cmds := cfg.Commands[prefix]
-	if _, ok := cmds[newShort]; ok {
-		return fmt.Errorf("short already exists: %s", newShort)
+// updatedEntry returns entry with the changes of an update applied.
+func updatedEntry(entry config.Entry, newCommand string, opts UpdateOptions, env map[string]string, steps []config.Step) config.Entry {
+	entry.Meta = entry.Meta.Clone()
+
+	if newCommand != "" {
+		entry.Command = newCommand
 	}
-	delete(cmds, oldShort)
 
-	cmds[newShort] = originalCmd
+	if len(steps) > 0 {
+		entry.Meta.Steps = steps
+		entry.Command = describeSteps(steps)
+	}
 
-	return nil
-}
+	if opts.Dir != nil {
+		entry.Meta.Dir = *opts.Dir
+	}
 
-func updateCommand(cfg *config.Config, prefix, short, newCommand string) error {
-	if newCommand == "" {
-		return nil
+	if opts.Shell != nil {
+		entry.Meta.Shell = *opts.Shell
 	}
 
-	cmds := cfg.Commands[prefix]
-	cmds[short] = newCommand
+	if len(env) > 0 || len(opts.UnsetEnv) > 0 {
+		merged := make(map[string]string, len(entry.Meta.Env)+len(env))
+		for key, value := range entry.Meta.Env {
+			merged[key] = value
+		}
+		for key, value := range env {
+			merged[key] = value
+		}
+		for _, key := range opts.UnsetEnv {
+			delete(merged, key)
+		}
+		entry.Meta.Env = merged
+	}
 
-	return nil
+	return entry
 }
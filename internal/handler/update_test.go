@@ -18,6 +18,8 @@ func TestUpdateCommand(t *testing.T) {
 		newPrefix     string
 		newShort      string
 		newCommand    string
+		force         bool
+		swap          bool
 		expectedError string
 		expectedCmds  map[string]map[string]string
 	}{
@@ -142,7 +144,129 @@ func TestUpdateCommand(t *testing.T) {
 			newPrefix:     "",
 			newShort:      "newShort",
 			newCommand:    "",
-			expectedError: "short already exists: newShort",
+			expectedError: "short already exists: oldPrefix newShort (use --force to replace it or --swap to exchange the two)",
+		},
+		{
+			name: "Fail update moving onto an existing short of another prefix",
+			initialConfig: &config.Config{
+				Commands: map[string]map[string]string{
+					"oldPrefix": {"oldShort": "original command"},
+					"newPrefix": {"oldShort": "existing command"},
+				},
+			},
+			oldPrefix:     "oldPrefix",
+			oldShort:      "oldShort",
+			newPrefix:     "newPrefix",
+			expectedError: "short already exists: newPrefix oldShort (use --force to replace it or --swap to exchange the two)",
+			expectedCmds: map[string]map[string]string{
+				"oldPrefix": {"oldShort": "original command"},
+				"newPrefix": {"oldShort": "existing command"},
+			},
+		},
+		{
+			name: "Replace an existing destination with force",
+			initialConfig: &config.Config{
+				Commands: map[string]map[string]string{
+					"oldPrefix": {"oldShort": "original command", "other": "other command"},
+					"newPrefix": {"newShort": "existing command"},
+				},
+			},
+			oldPrefix:  "oldPrefix",
+			oldShort:   "oldShort",
+			newPrefix:  "newPrefix",
+			newShort:   "newShort",
+			newCommand: "updated command",
+			force:      true,
+			expectedCmds: map[string]map[string]string{
+				"oldPrefix": {"other": "other command"},
+				"newPrefix": {"newShort": "updated command"},
+			},
+		},
+		{
+			name: "Swap with an existing destination",
+			initialConfig: &config.Config{
+				Commands: map[string]map[string]string{
+					"oldPrefix": {"oldShort": "original command"},
+					"newPrefix": {"newShort": "existing command"},
+				},
+			},
+			oldPrefix:  "oldPrefix",
+			oldShort:   "oldShort",
+			newPrefix:  "newPrefix",
+			newShort:   "newShort",
+			newCommand: "updated command",
+			swap:       true,
+			expectedCmds: map[string]map[string]string{
+				"oldPrefix": {"oldShort": "existing command"},
+				"newPrefix": {"newShort": "updated command"},
+			},
+		},
+		{
+			name: "Fail swap without a destination",
+			initialConfig: &config.Config{
+				Commands: map[string]map[string]string{
+					"oldPrefix": {"oldShort": "original command"},
+				},
+			},
+			oldPrefix:     "oldPrefix",
+			oldShort:      "oldShort",
+			newShort:      "newShort",
+			swap:          true,
+			expectedError: "nothing to swap with: oldPrefix newShort does not exist",
+		},
+		{
+			name: "Fail update with both force and swap",
+			initialConfig: &config.Config{
+				Commands: map[string]map[string]string{
+					"oldPrefix": {"oldShort": "original command"},
+				},
+			},
+			oldPrefix:     "oldPrefix",
+			oldShort:      "oldShort",
+			newShort:      "newShort",
+			force:         true,
+			swap:          true,
+			expectedError: "--force and --swap cannot be used together",
+		},
+		{
+			name: "Fail update with a valid new prefix and an invalid new short",
+			initialConfig: &config.Config{
+				Commands: map[string]map[string]string{
+					"oldPrefix": {"oldShort": "original command"},
+				},
+			},
+			oldPrefix:     "oldPrefix",
+			oldShort:      "oldShort",
+			newPrefix:     "newPrefix",
+			newShort:      "new short",
+			expectedError: "invalid short name 'new short': a space is not allowed; use letters, digits, '-' or '_'",
+			expectedCmds: map[string]map[string]string{
+				"oldPrefix": {"oldShort": "original command"},
+			},
+		},
+		{
+			name: "Fail update with an invalid new prefix",
+			initialConfig: &config.Config{
+				Commands: map[string]map[string]string{
+					"oldPrefix": {"oldShort": "original command"},
+				},
+			},
+			oldPrefix:     "oldPrefix",
+			oldShort:      "oldShort",
+			newPrefix:     "new.prefix",
+			expectedError: "invalid prefix 'new.prefix': '.' is not allowed; use letters, digits, '-' or '_'",
+		},
+		{
+			name: "Fail update with a reserved new short",
+			initialConfig: &config.Config{
+				Commands: map[string]map[string]string{
+					"oldPrefix": {"oldShort": "original command"},
+				},
+			},
+			oldPrefix:     "oldPrefix",
+			oldShort:      "oldShort",
+			newShort:      "NUL",
+			expectedError: "invalid short name 'NUL': reserved as a file name on Windows",
 		},
 	}
 
@@ -160,16 +284,17 @@ func TestUpdateCommand(t *testing.T) {
 				}
 			}
 
-			err = handler.UpdateCommand(tempFile.Name(), tt.oldPrefix, tt.oldShort, tt.newPrefix, tt.newShort, tt.newCommand)
+			opts := handler.UpdateOptions{Force: tt.force, Swap: tt.swap}
+			err = handler.UpdateCommand(tempFile.Name(), tt.oldPrefix, tt.oldShort, tt.newPrefix, tt.newShort, tt.newCommand, opts)
 
 			if tt.expectedError != "" {
 				if err == nil || err.Error() != tt.expectedError {
 					t.Errorf("unexpected error: got %v, want %v", err, tt.expectedError)
 				}
-				return
-			}
-
-			if err != nil {
+				if tt.expectedCmds == nil {
+					return
+				}
+			} else if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
 
@@ -183,3 +308,87 @@ func TestUpdateCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateCommandSettings(t *testing.T) {
+	configPath := t.TempDir() + "/config.toml"
+
+	opts := handler.AddOptions{Dir: "~/infra", Env: []string{"AWS_PROFILE=prod", "AWS_REGION=eu-west-1"}, Shell: "bash"}
+	if err := handler.AddCommand(configPath, "aws", "ls", "aws s3 ls", opts); err != nil {
+		t.Fatalf("AddCommand() error = %v", err)
+	}
+
+	dir := ""
+	shell := "zsh"
+	update := handler.UpdateOptions{Dir: &dir, Env: []string{"AWS_PROFILE=dev"}, UnsetEnv: []string{"AWS_REGION"}, Shell: &shell}
+	if err := handler.UpdateCommand(configPath, "aws", "ls", "cloud", "list", "", update); err != nil {
+		t.Fatalf("UpdateCommand() error = %v", err)
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config after execution: %v", err)
+	}
+
+	want := config.Entry{Command: "aws s3 ls", Meta: config.EntryMeta{Env: map[string]string{"AWS_PROFILE": "dev"}, Shell: "zsh"}}
+	if got, _ := cfg.GetEntry("cloud", "list"); !got.Equal(want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if _, ok := cfg.Meta["aws"]; ok {
+		t.Errorf("settings left behind under the old name: %v", cfg.Meta)
+	}
+
+	err = handler.AddCommand(configPath, "aws", "bad", "aws s3 ls", handler.AddOptions{Env: []string{"AWS_PROFILE"}})
+	if err == nil || err.Error() != "invalid environment variable 'AWS_PROFILE': use KEY=VALUE" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestUpdateCommandMovesWorkflowSteps(t *testing.T) {
+	configPath, err := createTempConfig(`
+[commands.aws]
+ls = "aws s3 ls"
+ls-dev = "aws s3 ls"
+
+[commands.release]
+ship = "aws:ls -> aws:ls-dev"
+
+[meta.aws.ls]
+env = { AWS_PROFILE = "prod" }
+
+[meta.aws.ls-dev]
+env = { AWS_PROFILE = "dev" }
+
+[[meta.release.ship.steps]]
+ref = "aws:ls"
+
+[[meta.release.ship.steps]]
+ref = "aws:ls-dev"
+`)
+	if err != nil {
+		t.Fatalf("failed to create temp config: %v", err)
+	}
+	defer cleanupTempFile(configPath)
+
+	captureStdout(t, func() {
+		err = handler.UpdateCommand(configPath, "aws", "ls", "", "ls-dev", "", handler.UpdateOptions{Swap: true})
+	})
+	if err != nil {
+		t.Fatalf("UpdateCommand() error = %v", err)
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config after execution: %v", err)
+	}
+	if profile := cfg.Meta["aws"]["ls"].Env["AWS_PROFILE"]; profile != "dev" {
+		t.Errorf("aws ls has AWS_PROFILE=%s, want dev", profile)
+	}
+	if profile := cfg.Meta["aws"]["ls-dev"].Env["AWS_PROFILE"]; profile != "prod" {
+		t.Errorf("aws ls-dev has AWS_PROFILE=%s, want prod", profile)
+	}
+
+	// The workflow keeps running the same commands under their new names.
+	if ship := cfg.Commands["release"]["ship"]; ship != "aws:ls-dev -> aws:ls" {
+		t.Errorf("release ship = %q, want %q", ship, "aws:ls-dev -> aws:ls")
+	}
+}
@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/remote"
+)
+
+// stepWhenSep separates a step reference from its condition in --step
+// values such as "notify:oncall@failed".
+const stepWhenSep = "@"
+
+type workflowRun struct {
+	cfg *config.Config
+	// from is the index of the first step to run.
+	from int
+}
+
+// parseSteps parses --step values of the form prefix:short[@condition].
+func parseSteps(specs []string) ([]config.Step, error) {
+	steps := make([]config.Step, 0, len(specs))
+	for _, spec := range specs {
+		var step config.Step
+		step.Ref, step.When, _ = strings.Cut(spec, stepWhenSep)
+		if step.When == config.WhenSucceeded {
+			step.When = ""
+		}
+
+		if _, err := domain.ParseRef(step.Ref); err != nil {
+			return nil, err
+		}
+		if err := validateWhen(step); err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func validateWhen(step config.Step) error {
+	switch step.When {
+	case "", config.WhenSucceeded, config.WhenFailed, config.WhenAlways:
+		return nil
+	}
+	return fmt.Errorf("invalid condition '%s' for step %s: use %s, %s or %s",
+		step.When, step.Ref, config.WhenSucceeded, config.WhenFailed, config.WhenAlways)
+}
+
+// describeSteps is stored as the command of a workflow so that it reads
+// sensibly wherever commands are shown.
+func describeSteps(steps []config.Step) string {
+	parts := make([]string, len(steps))
+	for i, step := range steps {
+		parts[i] = step.Ref
+		if step.When != "" {
+			parts[i] += stepWhenSep + step.When
+		}
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// retargetSteps points the workflow steps running an entry in moved, keyed
+// by prefix:short, at the entry it maps to.
+func retargetSteps(cfg *config.Config, moved map[string]string) {
+	for prefix, metas := range cfg.Meta {
+		for short, meta := range metas {
+			changed := false
+			for i, step := range meta.Steps {
+				if to, ok := moved[step.Ref]; ok {
+					meta.Steps[i].Ref = to
+					changed = true
+				}
+			}
+			if changed {
+				cfg.Commands[prefix][short] = describeSteps(meta.Steps)
+			}
+		}
+	}
+}
+
+// workflowsRunning returns the workflows with a step running one of refs,
+// keyed by prefix:short.
+func workflowsRunning(cfg *config.Config, refs map[string]string) []domain.Ref {
+	var workflows []domain.Ref
+	for prefix, metas := range cfg.Meta {
+		for short, meta := range metas {
+			if slices.ContainsFunc(meta.Steps, func(step config.Step) bool { _, ok := refs[step.Ref]; return ok }) {
+				workflows = append(workflows, domain.Ref{Prefix: prefix, Short: short})
+			}
+		}
+	}
+	sort.Slice(workflows, func(i, j int) bool { return workflows[i].String() < workflows[j].String() })
+	return workflows
+}
+
+// prepareWorkflow resolves every step reachable from the workflow, failing
+// on missing entries and cycles, and confirms high-risk steps before any of
+// them runs.
+func prepareWorkflow(configPath string, cfg *config.Config, ref domain.Ref, entry config.Entry, opts ExecOptions) (execJob, error) {
+	if len(opts.Args) > 0 {
+		return execJob{}, fmt.Errorf("arguments only apply to commands: %s %s is a workflow", ref.Prefix, ref.Short)
+	}
+
+	from, err := findStep(entry.Meta.Steps, opts.From)
+	if err != nil {
+		return execJob{}, err
+	}
+
+	confirmed := make(map[domain.Ref]bool)
+	if err := checkSteps(configPath, cfg, entry.Meta.Steps[from:], []domain.Ref{ref}, confirmed, opts); err != nil {
+		return execJob{}, err
+	}
+
+	return execJob{
+		configPath: configPath,
+		ref:        ref,
+		entry:      entry,
+		workflow:   &workflowRun{cfg: cfg, from: from},
+	}, nil
+}
+
+func checkSteps(configPath string, cfg *config.Config, steps []config.Step, path []domain.Ref, confirmed map[domain.Ref]bool, opts ExecOptions) error {
+	for _, step := range steps {
+		if err := validateWhen(step); err != nil {
+			return err
+		}
+		ref, err := stepRef(cfg, path[len(path)-1], step)
+		if err != nil {
+			return err
+		}
+
+		// Copy the path so that sibling steps do not share its backing array.
+		next := append(path[:len(path):len(path)], ref)
+		for i, seen := range path {
+			if seen == ref {
+				return fmt.Errorf("workflow cycle: %s", formatCycle(next[i:]))
+			}
+		}
+
+		entry, err := lookupCommand(configPath, cfg, ref.Prefix, ref.Short)
+		if err != nil {
+			return fmt.Errorf("step %s of %s: %w", ref, path[len(path)-1], err)
+		}
+
+		if entry.IsWorkflow() {
+			if err := checkSteps(configPath, cfg, entry.Meta.Steps, next, confirmed, opts); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Missing template values fail the workflow before any step runs.
+		command, _, err := renderCommand(ref, entry.Command, opts)
+		if err != nil {
+			return err
+		}
+
+		if !confirmed[ref] {
+			if err := confirmRisk(cfg, ref.Prefix, ref.Short, command, opts); err != nil {
+				return err
+			}
+			confirmed[ref] = true
+		}
+	}
+	return nil
+}
+
+// stepRef returns the entry run by a step of the workflow named by parent.
+// The steps of a workflow from a remote book refer to entries of that book,
+// so they are resolved in the remote's namespace.
+func stepRef(cfg *config.Config, parent domain.Ref, step config.Step) (domain.Ref, error) {
+	ref, err := domain.ParseRef(step.Ref)
+	if err != nil {
+		return domain.Ref{}, err
+	}
+	if name, _, ok := remote.SplitNamespace(parent.Prefix); ok {
+		if _, isRemote := cfg.Remotes[name]; isRemote {
+			ref.Prefix = remote.Namespace(name, ref.Prefix)
+		}
+	}
+	return ref, nil
+}
+
+func formatCycle(refs []domain.Ref) string {
+	parts := make([]string, len(refs))
+	for i, ref := range refs {
+		parts[i] = ref.String()
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// findStep returns the index of the step named by from, given as a 1-based
+// number or as the reference of the step.
+func findStep(steps []config.Step, from string) (int, error) {
+	if from == "" {
+		return 0, nil
+	}
+
+	if n, err := strconv.Atoi(from); err == nil {
+		if n < 1 || n > len(steps) {
+			return 0, fmt.Errorf("step %d out of range: the workflow has %d steps", n, len(steps))
+		}
+		return n - 1, nil
+	}
+
+	for i, step := range steps {
+		if step.Ref == from {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("step not found: %s", from)
+}
+
+// runWorkflow runs the steps in order through the single-entry path. Steps
+// run when their condition matches the outcome of the steps before them;
+// the first failure is returned once every step has been considered.
+func (j execJob) runWorkflow(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, opts ExecOptions) error {
+	// Every step was confirmed while preparing the workflow.
+	opts.Yes = true
+	opts.From = ""
+
+	steps := j.entry.Meta.Steps
+	var failure error
+
+	for i := j.workflow.from; i < len(steps); i++ {
+		step := steps[i]
+		ref, _ := stepRef(j.workflow.cfg, j.ref, step)
+		if !shouldRunStep(step, failure != nil) {
+			fmt.Fprintf(stderr, "--> [%d/%d] %s skipped\n", i+1, len(steps), ref)
+			continue
+		}
+		fmt.Fprintf(stderr, "--> [%d/%d] %s\n", i+1, len(steps), ref)
+
+		job, err := prepareJob(j.configPath, j.workflow.cfg, ref, opts)
+		if err == nil {
+			job.policy.processGroup = job.policy.processGroup || j.policy.processGroup
+			err = job.run(ctx, stdin, stdout, stderr, opts)
+		}
+
+		if errors.Is(err, errInterrupted) {
+			return err
+		}
+		if err != nil && failure == nil {
+			failure = &stepError{step: i + 1, ref: ref, err: err}
+		}
+	}
+	return failure
+}
+
+// stepError reports the first failed step of a workflow.
+type stepError struct {
+	step int
+	ref  domain.Ref
+	err  error
+}
+
+func (e *stepError) Error() string {
+	return fmt.Sprintf("step %d (%s) failed: %v", e.step, e.ref, e.err)
+}
+
+func (e *stepError) Unwrap() error {
+	return e.err
+}
+
+func shouldRunStep(step config.Step, failed bool) bool {
+	switch step.When {
+	case config.WhenFailed:
+		return failed
+	case config.WhenAlways:
+		return true
+	default:
+		return !failed
+	}
+}
@@ -0,0 +1,170 @@
+package handler_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/handler"
+)
+
+func TestExecCommandWorkflow(t *testing.T) {
+	tests := []struct {
+		name          string
+		short         string
+		from          string
+		expectedError string
+		expectedLog   string
+	}{
+		{
+			name:        "runs the steps in order",
+			short:       "deploy",
+			expectedLog: "build push rollout ",
+		},
+		{
+			name:          "failure skips later steps except failure handlers",
+			short:         "broken",
+			expectedError: "step 2 (job:fail) failed: exit status 1",
+			expectedLog:   "build notify cleanup ",
+		},
+		{
+			name:        "resumes from a step number",
+			short:       "deploy",
+			from:        "3",
+			expectedLog: "rollout ",
+		},
+		{
+			name:        "resumes from a step reference",
+			short:       "deploy",
+			from:        "job:push",
+			expectedLog: "push rollout ",
+		},
+		{
+			name:        "runs nested workflows",
+			short:       "release",
+			expectedLog: "build push rollout cleanup ",
+		},
+		{
+			name:          "detects cycles before running anything",
+			short:         "ping",
+			expectedError: "workflow cycle: flow:ping -> flow:pong -> flow:ping",
+		},
+		{
+			name:          "reports missing steps before running anything",
+			short:         "missing",
+			expectedError: "step job:nope of flow:missing: command not found: job nope",
+		},
+		{
+			name:          "unknown step to resume from",
+			short:         "deploy",
+			from:          "job:nope",
+			expectedError: "step not found: job:nope",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPath := filepath.Join(t.TempDir(), "log")
+			configPath, err := createTempConfig(`
+[commands.job]
+build = "printf 'build ' >> ` + logPath + `"
+push = "printf 'push ' >> ` + logPath + `"
+rollout = "printf 'rollout ' >> ` + logPath + `"
+notify = "printf 'notify ' >> ` + logPath + `"
+cleanup = "printf 'cleanup ' >> ` + logPath + `"
+fail = "exit 1"
+
+[commands.flow]
+deploy = "job:build -> job:push -> job:rollout"
+broken = "job:build -> job:fail -> job:push -> job:notify@failed -> job:cleanup@always"
+release = "flow:deploy -> job:cleanup"
+ping = "flow:pong"
+pong = "flow:ping"
+missing = "job:build -> job:nope"
+
+[meta.flow.deploy]
+steps = [{ ref = "job:build" }, { ref = "job:push" }, { ref = "job:rollout" }]
+
+[meta.flow.broken]
+steps = [
+  { ref = "job:build" },
+  { ref = "job:fail" },
+  { ref = "job:push" },
+  { ref = "job:notify", when = "failed" },
+  { ref = "job:cleanup", when = "always" },
+]
+
+[meta.flow.release]
+steps = [{ ref = "flow:deploy" }, { ref = "job:cleanup" }]
+
+[meta.flow.ping]
+steps = [{ ref = "flow:pong" }]
+
+[meta.flow.pong]
+steps = [{ ref = "flow:ping" }]
+
+[meta.flow.missing]
+steps = [{ ref = "job:build" }, { ref = "job:nope" }]
+`)
+			if err != nil {
+				t.Fatalf("failed to create temp config file: %v", err)
+			}
+			defer cleanupTempFile(configPath)
+
+			err = handler.ExecCommand(configPath, "flow", tt.short, handler.ExecOptions{From: tt.from})
+			if (err != nil && err.Error() != tt.expectedError) || (err == nil && tt.expectedError != "") {
+				t.Errorf("unexpected error: got %v, want %v", err, tt.expectedError)
+			}
+
+			data, _ := os.ReadFile(logPath)
+			if string(data) != tt.expectedLog {
+				t.Errorf("steps ran as %q, want %q", data, tt.expectedLog)
+			}
+		})
+	}
+}
+
+func TestAddWorkflow(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+
+	opts := handler.AddOptions{Steps: []string{"build:image", "k8s:rollback@failed"}}
+	if err := handler.AddCommand(configPath, "release", "deploy", "", opts); err != nil {
+		t.Fatalf("AddCommand() error = %v", err)
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config after execution: %v", err)
+	}
+	entry, _ := cfg.GetEntry("release", "deploy")
+	want := config.Entry{
+		Command: "build:image -> k8s:rollback@failed",
+		Meta: config.EntryMeta{Steps: []config.Step{
+			{Ref: "build:image"},
+			{Ref: "k8s:rollback", When: config.WhenFailed},
+		}},
+	}
+	if !entry.Equal(want) {
+		t.Errorf("got %+v, want %+v", entry, want)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		command string
+		steps   []string
+		want    string
+	}{
+		{name: "command and steps", command: "echo", steps: []string{"build:image"}, want: "a workflow takes steps instead of a command"},
+		{name: "bad condition", steps: []string{"build:image@sometimes"}, want: "invalid condition 'sometimes' for step build:image"},
+		{name: "bad reference", steps: []string{"build"}, want: "invalid command reference 'build'"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := handler.AddCommand(configPath, "release", "other", tt.command, handler.AddOptions{Steps: tt.steps})
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("unexpected error: got %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+)
+
+const fileName = "history.jsonl"
+
+// Record describes one execution of an entry.
+type Record struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Prefix     string    `json:"prefix"`
+	Short      string    `json:"short"`
+	Command    string    `json:"command"`
+	Dir        string    `json:"dir"`
+	DurationMS int64     `json:"duration_ms"`
+	ExitCode   int       `json:"exit_code"`
+	Host       string    `json:"host"`
+	// Error explains failures that have no exit code, such as timeouts.
+	Error string `json:"error,omitempty"`
+	// Output is set when the output of the run was captured.
+	Output bool `json:"output,omitempty"`
+	// Set holds the values given to the template variables of the command.
+	Set map[string]string `json:"set,omitempty"`
+}
+
+func (r Record) Failed() bool {
+	return r.ExitCode != 0 || r.Error != ""
+}
+
+func (r Record) Duration() time.Duration {
+	return time.Duration(r.DurationMS) * time.Millisecond
+}
+
+type Filter struct {
+	Failed bool
+	// Since drops records older than this time when it is set.
+	Since  time.Time
+	Prefix string
+	Short  string
+}
+
+func (f Filter) match(r Record) bool {
+	return (!f.Failed || r.Failed()) &&
+		(f.Since.IsZero() || !r.Time.Before(f.Since)) &&
+		(f.Prefix == "" || r.Prefix == f.Prefix) &&
+		(f.Short == "" || r.Short == f.Short)
+}
+
+func Path(configPath string) string {
+	return filepath.Join(config.DataDir(configPath), fileName)
+}
+
+// NewID returns an identifier for a run starting at t that sorts by time.
+func NewID(t time.Time) string {
+	suffix := make([]byte, 2)
+	_, _ = rand.Read(suffix)
+	return t.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// appendMu serializes appends from commands running in parallel.
+var appendMu sync.Mutex
+
+func Append(configPath string, r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	appendMu.Lock()
+	defer appendMu.Unlock()
+
+	if err := os.MkdirAll(config.DataDir(configPath), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(Path(configPath), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// Load returns the records matching f, oldest first. Lines that cannot be
+// parsed, such as one cut short by a crash, are skipped.
+func Load(configPath string, f Filter) ([]Record, error) {
+	file, err := os.Open(Path(configPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		if f.match(r) {
+			records = append(records, r)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// Rename files the records of every entry in moved under the entry it maps
+// to, so that its statistics follow it to its new name. Lines that cannot
+// be parsed are kept as they are.
+func Rename(configPath string, moved map[domain.Ref]domain.Ref) error {
+	appendMu.Lock()
+	defer appendMu.Unlock()
+
+	data, err := os.ReadFile(Path(configPath))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	changed := false
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		var r Record
+		if err := json.Unmarshal(line, &r); err == nil {
+			if to, ok := moved[domain.Ref{Prefix: r.Prefix, Short: r.Short}]; ok {
+				r.Prefix, r.Short = to.Prefix, to.Short
+				if line, err = json.Marshal(r); err != nil {
+					return err
+				}
+				line = append(line, '\n')
+				changed = true
+			}
+		}
+		out = append(out, line...)
+	}
+	if !changed {
+		return nil
+	}
+
+	tmp := Path(configPath) + ".tmp"
+	if err := os.WriteFile(tmp, out, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, Path(configPath))
+}
+
+// ParseAge parses durations such as "90m", "12h", "1d" or "2w".
+func ParseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age '%s': use a duration such as 12h, 1d or 2w", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age '%s': use a duration such as 12h, 1d or 2w", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,136 @@
+package history_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/history"
+)
+
+func TestAppendLoad(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	now := time.Now()
+
+	records := []history.Record{
+		{ID: "1", Time: now.Add(-48 * time.Hour), Prefix: "git", Short: "st", Command: "git status"},
+		{ID: "2", Time: now.Add(-time.Hour), Prefix: "git", Short: "push", Command: "git push", ExitCode: 1},
+		{ID: "3", Time: now, Prefix: "docker", Short: "up", Command: "docker compose up", ExitCode: -1, Error: "timed out after 30s"},
+	}
+	for _, r := range records {
+		if err := history.Append(configPath, r); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	// A line cut short by a crash must not hide the others.
+	file, err := os.OpenFile(history.Path(configPath), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"id": "4", "ti`)
+	file.Close()
+
+	tests := []struct {
+		name   string
+		filter history.Filter
+		want   []string
+	}{
+		{name: "everything", want: []string{"1", "2", "3"}},
+		{name: "failed", filter: history.Filter{Failed: true}, want: []string{"2", "3"}},
+		{name: "since", filter: history.Filter{Since: now.Add(-24 * time.Hour)}, want: []string{"2", "3"}},
+		{name: "prefix", filter: history.Filter{Prefix: "git"}, want: []string{"1", "2"}},
+		{name: "combined", filter: history.Filter{Failed: true, Prefix: "git"}, want: []string{"2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := history.Load(configPath, tt.filter)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d records, want %v", len(got), tt.want)
+			}
+			for i, r := range got {
+				if r.ID != tt.want[i] {
+					t.Errorf("record %d: got %s, want %s", i, r.ID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRename(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	for _, r := range []history.Record{
+		{ID: "1", Prefix: "git", Short: "st"},
+		{ID: "2", Prefix: "git", Short: "push"},
+		{ID: "3", Prefix: "docker", Short: "up"},
+	} {
+		if err := history.Append(configPath, r); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	// Entries exchanging their names swap their records.
+	moved := map[domain.Ref]domain.Ref{
+		{Prefix: "git", Short: "st"}:    {Prefix: "git", Short: "push"},
+		{Prefix: "git", Short: "push"}:  {Prefix: "git", Short: "st"},
+		{Prefix: "docker", Short: "up"}: {Prefix: "container", Short: "up"},
+	}
+	if err := history.Rename(configPath, moved); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+
+	records, err := history.Load(configPath, history.Filter{})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := []string{"1 git:push", "2 git:st", "3 container:up"}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %v", len(records), want)
+	}
+	for i, r := range records {
+		if got := r.ID + " " + r.Prefix + ":" + r.Short; got != want[i] {
+			t.Errorf("record %d: got %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	records, err := history.Load(filepath.Join(t.TempDir(), "config.toml"), history.Filter{})
+	if err != nil || len(records) != 0 {
+		t.Errorf("Load() = %v, %v; want no records", records, err)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    time.Duration
+		expectError bool
+	}{
+		{input: "1d", expected: 24 * time.Hour},
+		{input: "2w", expected: 14 * 24 * time.Hour},
+		{input: "180d", expected: 180 * 24 * time.Hour},
+		{input: "90m", expected: 90 * time.Minute},
+		{input: "d", expectError: true},
+		{input: "-1d", expectError: true},
+		{input: "soon", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := history.ParseAge(tt.input)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("ParseAge(%q) error = %v, expectError %v", tt.input, err, tt.expectError)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseAge(%q) = %s, want %s", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
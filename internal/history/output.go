@@ -0,0 +1,121 @@
+package history
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+)
+
+const (
+	outputDirName = "outputs"
+	outputExt     = ".log"
+
+	// MaxOutputSize caps a captured output; the rest is still shown but not
+	// stored.
+	MaxOutputSize = 1 << 20
+	// KeepOutputs is the number of captured outputs kept; older ones are
+	// removed after each capture.
+	KeepOutputs = 20
+)
+
+func outputDir(configPath string) string {
+	return filepath.Join(config.DataDir(configPath), outputDirName)
+}
+
+func OutputPath(configPath, id string) string {
+	return filepath.Join(outputDir(configPath), id+outputExt)
+}
+
+// Capture stores the output of the run with the given ID.
+type Capture struct {
+	mu        sync.Mutex
+	file      *os.File
+	written   int
+	truncated bool
+}
+
+func NewCapture(configPath, id string) (*Capture, error) {
+	if err := os.MkdirAll(outputDir(configPath), 0o700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(OutputPath(configPath, id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &Capture{file: file}, nil
+}
+
+// Tee returns a writer copying everything written to w into the capture.
+// Writers of the same capture may be used concurrently.
+func (c *Capture) Tee(w io.Writer) io.Writer {
+	return io.MultiWriter(w, (*captureWriter)(c))
+}
+
+type captureWriter Capture
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	c := (*Capture)(w)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	room := MaxOutputSize - c.written
+	if len(data) > room {
+		c.truncated = true
+		if room > 0 {
+			n, _ := c.file.Write(data[:room])
+			c.written += n
+		}
+		return len(data), nil
+	}
+
+	// Failing to store the output must not interrupt the command.
+	n, _ := c.file.Write(data)
+	c.written += n
+	return len(data), nil
+}
+
+func (c *Capture) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.truncated {
+		fmt.Fprintf(c.file, "\n[cmdbook: output truncated after %d bytes]\n", MaxOutputSize)
+	}
+	return c.file.Close()
+}
+
+// CleanupOutputs removes all but the KeepOutputs most recent outputs.
+func CleanupOutputs(configPath string) error {
+	entries, err := os.ReadDir(outputDir(configPath))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), outputExt) {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) <= KeepOutputs {
+		return nil
+	}
+
+	// Run IDs start with their time, so names sort from oldest to newest.
+	sort.Strings(names)
+	for _, name := range names[:len(names)-KeepOutputs] {
+		if err := os.Remove(filepath.Join(outputDir(configPath), name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package history_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/history"
+)
+
+func TestCapture(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+
+	capture, err := history.NewCapture(configPath, "run")
+	if err != nil {
+		t.Fatalf("NewCapture() error = %v", err)
+	}
+
+	var shown bytes.Buffer
+	w := capture.Tee(&shown)
+	big := strings.Repeat("x", history.MaxOutputSize)
+	fmt.Fprint(w, "hello\n")
+	fmt.Fprint(w, big)
+	if err := capture.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if shown.Len() != len("hello\n")+len(big) {
+		t.Errorf("the terminal must get the whole output, got %d bytes", shown.Len())
+	}
+
+	data, err := os.ReadFile(history.OutputPath(configPath, "run"))
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "hello\nxxx") || !strings.Contains(string(data), "output truncated") {
+		t.Errorf("unexpected output: %q...", data[:20])
+	}
+	if len(data) > history.MaxOutputSize+100 {
+		t.Errorf("stored %d bytes, want at most about %d", len(data), history.MaxOutputSize)
+	}
+}
+
+func TestCleanupOutputs(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var ids []string
+	for i := 0; i < history.KeepOutputs+3; i++ {
+		id := history.NewID(start.Add(time.Duration(i) * time.Minute))
+		ids = append(ids, id)
+
+		capture, err := history.NewCapture(configPath, id)
+		if err != nil {
+			t.Fatalf("NewCapture() error = %v", err)
+		}
+		capture.Close()
+	}
+
+	if err := history.CleanupOutputs(configPath); err != nil {
+		t.Fatalf("CleanupOutputs() error = %v", err)
+	}
+
+	for i, id := range ids {
+		_, err := os.Stat(history.OutputPath(configPath, id))
+		if kept := err == nil; kept != (i >= 3) {
+			t.Errorf("output %d kept = %v", i, kept)
+		}
+	}
+}
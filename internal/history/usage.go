@@ -0,0 +1,71 @@
+package history
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+)
+
+// halfLife is the age at which a run counts half as much towards frecency.
+const halfLife = 7 * 24 * time.Hour
+
+type Usage struct {
+	Ref      domain.Ref
+	Count    int
+	Failures int
+	LastUsed time.Time
+	// Score is the frecency: every run counts, recent ones the most.
+	Score float64
+}
+
+// Usages aggregates records per entry as of now.
+func Usages(records []Record, now time.Time) map[domain.Ref]Usage {
+	usages := make(map[domain.Ref]Usage)
+	for _, r := range records {
+		ref := domain.Ref{Prefix: r.Prefix, Short: r.Short}
+		u := usages[ref]
+		u.Ref = ref
+		u.Count++
+		if r.Failed() {
+			u.Failures++
+		}
+		if r.Time.After(u.LastUsed) {
+			u.LastUsed = r.Time
+		}
+		u.Score += math.Exp2(-float64(now.Sub(r.Time)) / float64(halfLife))
+		usages[ref] = u
+	}
+	return usages
+}
+
+// Scores returns the frecency of every entry run from the book at
+// configPath. A missing or unreadable history yields no scores, so callers
+// fall back to alphabetical order.
+func Scores(configPath string) map[domain.Ref]float64 {
+	records, err := Load(configPath, Filter{})
+	if err != nil {
+		return nil
+	}
+
+	scores := make(map[domain.Ref]float64)
+	for ref, u := range Usages(records, time.Now()) {
+		scores[ref] = u.Score
+	}
+	return scores
+}
+
+// Rank sorts refs by descending score, then by name.
+func Rank(refs []domain.Ref, scores map[domain.Ref]float64) {
+	sort.SliceStable(refs, func(i, j int) bool {
+		si, sj := scores[refs[i]], scores[refs[j]]
+		if si != sj {
+			return si > sj
+		}
+		if refs[i].Prefix != refs[j].Prefix {
+			return refs[i].Prefix < refs[j].Prefix
+		}
+		return refs[i].Short < refs[j].Short
+	})
+}
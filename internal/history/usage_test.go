@@ -0,0 +1,48 @@
+package history_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/history"
+)
+
+func TestUsages(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	records := []history.Record{
+		{Prefix: "git", Short: "st", Time: now.Add(-60 * day)},
+		{Prefix: "git", Short: "st", Time: now.Add(-59 * day)},
+		{Prefix: "git", Short: "st", Time: now.Add(-58 * day), ExitCode: 1},
+		{Prefix: "git", Short: "push", Time: now.Add(-time.Hour)},
+	}
+
+	usages := history.Usages(records, now)
+
+	st := usages[domain.Ref{Prefix: "git", Short: "st"}]
+	if st.Count != 3 || st.Failures != 1 || !st.LastUsed.Equal(now.Add(-58*day)) {
+		t.Errorf("unexpected usage: %+v", st)
+	}
+
+	push := usages[domain.Ref{Prefix: "git", Short: "push"}]
+	if push.Score <= st.Score {
+		t.Errorf("a run an hour ago (%.3f) should outrank three runs two months ago (%.3f)", push.Score, st.Score)
+	}
+	if push.Score > 1 {
+		t.Errorf("a single run scores at most 1, got %.3f", push.Score)
+	}
+}
+
+func TestRank(t *testing.T) {
+	refs := []domain.Ref{{Prefix: "git", Short: "st"}, {Prefix: "docker", Short: "up"}, {Prefix: "git", Short: "co"}, {Prefix: "git", Short: "push"}}
+	scores := map[domain.Ref]float64{{Prefix: "git", Short: "push"}: 2, {Prefix: "git", Short: "st"}: 0.5}
+
+	history.Rank(refs, scores)
+
+	want := []domain.Ref{{Prefix: "git", Short: "push"}, {Prefix: "git", Short: "st"}, {Prefix: "docker", Short: "up"}, {Prefix: "git", Short: "co"}}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("Rank() = %v, want %v", refs, want)
+	}
+}
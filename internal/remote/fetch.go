@@ -0,0 +1,151 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/signature"
+)
+
+const (
+	fetchTimeout     = 30 * time.Second
+	maxBookSize      = 10 << 20
+	maxSignatureSize = 4 << 10
+)
+
+var DefaultClient = &http.Client{Timeout: fetchTimeout}
+
+// Update fetches the book of remote r into the cache of the remote called
+// name, sending the validators of the cached copy so the server can answer
+// 304 Not Modified. It reports whether the cached book changed. The fetched
+// book replaces the cached copy only once it passes verification against r
+// and trustedKeys; otherwise the cached copy is kept.
+func Update(client *http.Client, configPath, name string, r config.Remote, trustedKeys map[string]string) (bool, error) {
+	rawURL := r.URL
+
+	cached, err := loadMeta(configPath, name)
+	if err != nil && !errors.Is(err, ErrNotFetched) {
+		return false, err
+	}
+	if cached.URL != rawURL {
+		cached = Meta{}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return false, err
+	}
+	if cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.LastModified)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return false, nil
+	case http.StatusOK:
+	default:
+		return false, fmt.Errorf("unexpected response from %s: %s", rawURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBookSize+1))
+	if err != nil {
+		return false, err
+	}
+	if len(data) > maxBookSize {
+		return false, fmt.Errorf("remote book exceeds %d bytes: %s", maxBookSize, rawURL)
+	}
+
+	meta := Meta{
+		URL:          rawURL,
+		Format:       detectFormat(rawURL, resp.Header.Get("Content-Type"), data),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+
+	if _, err := config.ParseConfig(data, meta.Format); err != nil {
+		return false, fmt.Errorf("invalid remote book %s: %w", rawURL, err)
+	}
+
+	sig, err := fetchSignature(client, rawURL)
+	if err != nil {
+		return false, err
+	}
+
+	staged := name + stagedSuffix
+	defer func() { _ = Remove(configPath, staged) }()
+	if err := saveCache(configPath, staged, data, sig, meta); err != nil {
+		return false, err
+	}
+	if err := verifyStaged(configPath, staged, r, trustedKeys); err != nil {
+		return false, fmt.Errorf("fetched book rejected, keeping the cached copy: %w", err)
+	}
+	if err := promoteCache(configPath, staged, name); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// verifyStaged verifies the staged copy of a book. A remote that pins the
+// key on first use and has none yet only needs a valid signature.
+func verifyStaged(configPath, staged string, r config.Remote, trustedKeys map[string]string) error {
+	if r.TrustOnFirstUse && r.Key == "" {
+		_, err := FirstUseKey(configPath, staged)
+		return err
+	}
+	return Verify(configPath, staged, r, trustedKeys)
+}
+
+// fetchSignature downloads the detached signature published next to the
+// book. It returns nil when the book is unsigned.
+func fetchSignature(client *http.Client, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path += signature.Ext
+
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(io.LimitReader(resp.Body, maxSignatureSize))
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected response for signature of %s: %s", rawURL, resp.Status)
+	}
+}
+
+func detectFormat(rawURL, contentType string, data []byte) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && strings.HasSuffix(mediaType, "json") {
+		return config.FormatJSON
+	}
+	if u, err := url.Parse(rawURL); err == nil && path.Ext(u.Path) == ".json" {
+		return config.FormatJSON
+	}
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "{") {
+		return config.FormatJSON
+	}
+	return config.FormatTOML
+}
@@ -0,0 +1,191 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/signature"
+)
+
+const (
+	NamespaceSep = ":"
+
+	cacheDirName = "remotes"
+	bookExt      = ".book"
+	metaExt      = ".json"
+	sigExt       = signature.Ext
+	// stagedSuffix marks the cache of a fetched book awaiting
+	// verification; remote names cannot contain a dot.
+	stagedSuffix = ".staged"
+)
+
+var ErrNotFetched = errors.New("remote book has not been fetched yet; run 'cb remote update'")
+
+// Meta describes the cached copy of a remote book.
+type Meta struct {
+	URL          string `json:"url"`
+	Format       string `json:"format"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// Namespace returns the prefix under which a remote's prefix is shown.
+func Namespace(name, prefix string) string {
+	return name + NamespaceSep + prefix
+}
+
+// SplitNamespace splits a namespaced prefix such as "team:docker" into the
+// remote name and the prefix inside the remote book.
+func SplitNamespace(prefix string) (string, string, bool) {
+	name, inner, ok := strings.Cut(prefix, NamespaceSep)
+	if !ok || name == "" || inner == "" {
+		return "", "", false
+	}
+	return name, inner, true
+}
+
+// Load returns the cached book of the remote called name.
+func Load(configPath, name string) (*config.Config, error) {
+	meta, err := loadMeta(configPath, name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(bookPath(configPath, name))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFetched
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return config.ParseConfig(data, meta.Format)
+}
+
+// WithRemotes returns a read-only view of cfg that also contains the cached
+// books of all remotes under namespaced prefixes. Remotes that have not been
+// fetched yet are skipped. The view must never be saved.
+func WithRemotes(configPath string, cfg *config.Config) (*config.Config, error) {
+	view := &config.Config{
+		Commands: make(map[string]map[string]string, len(cfg.Commands)),
+		Remotes:  cfg.Remotes,
+	}
+	for prefix, cmds := range cfg.Commands {
+		view.Commands[prefix] = cmds
+	}
+
+	names := make([]string, 0, len(cfg.Remotes))
+	for name := range cfg.Remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		book, err := Load(configPath, name)
+		if errors.Is(err, ErrNotFetched) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load remote %s: %w", name, err)
+		}
+
+		for prefix, cmds := range book.Commands {
+			view.Commands[Namespace(name, prefix)] = cmds
+		}
+	}
+
+	return view, nil
+}
+
+// Remove deletes the cached copy of a remote book.
+func Remove(configPath, name string) error {
+	for _, path := range []string{bookPath(configPath, name), metaPath(configPath, name), sigPath(configPath, name)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func cacheDir(configPath string) string {
+	return filepath.Join(config.DataDir(configPath), cacheDirName)
+}
+
+func bookPath(configPath, name string) string {
+	return filepath.Join(cacheDir(configPath), name+bookExt)
+}
+
+func sigPath(configPath, name string) string {
+	return filepath.Join(cacheDir(configPath), name+sigExt)
+}
+
+func metaPath(configPath, name string) string {
+	return filepath.Join(cacheDir(configPath), name+metaExt)
+}
+
+func loadMeta(configPath, name string) (Meta, error) {
+	var meta Meta
+
+	data, err := os.ReadFile(metaPath(configPath, name))
+	if os.IsNotExist(err) {
+		return meta, ErrNotFetched
+	}
+	if err != nil {
+		return meta, err
+	}
+
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, fmt.Errorf("corrupt cache for remote %s: %w", name, err)
+	}
+	return meta, nil
+}
+
+// promoteCache moves the cached files of from over those of to. The
+// metadata goes last, so that an interrupted move is fetched again.
+func promoteCache(configPath, from, to string) error {
+	if err := os.Rename(bookPath(configPath, from), bookPath(configPath, to)); err != nil {
+		return err
+	}
+
+	err := os.Rename(sigPath(configPath, from), sigPath(configPath, to))
+	if errors.Is(err, os.ErrNotExist) {
+		err = os.Remove(sigPath(configPath, to))
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return os.Rename(metaPath(configPath, from), metaPath(configPath, to))
+}
+
+// saveCache stores a fetched book. A nil sig removes any cached signature.
+func saveCache(configPath, name string, data, sig []byte, meta Meta) error {
+	if err := os.MkdirAll(cacheDir(configPath), 0755); err != nil {
+		return err
+	}
+
+	metaData, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(bookPath(configPath, name), data, 0644); err != nil {
+		return err
+	}
+
+	if sig == nil {
+		if err := os.Remove(sigPath(configPath, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else if err := os.WriteFile(sigPath(configPath, name), sig, 0644); err != nil {
+		return err
+	}
+
+	return os.WriteFile(metaPath(configPath, name), metaData, 0644)
+}
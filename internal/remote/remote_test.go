@@ -0,0 +1,201 @@
+package remote_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/remote"
+	"github.com/pHo9UBenaA/cmdbook/internal/signature"
+)
+
+const teamBook = `
+[commands.docker]
+up = "docker compose up"
+`
+
+// unsigned serves a book without a detached signature
+func unsigned(serveBook http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, signature.Ext) {
+			http.NotFound(w, r)
+			return
+		}
+		serveBook(w, r)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	var requests []*http.Request
+	body := teamBook
+	etag := `"v1"`
+
+	server := httptest.NewServer(unsigned(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	url := server.URL + "/team.toml"
+
+	updated, err := remote.Update(server.Client(), configPath, "team", config.Remote{URL: url}, nil)
+	if err != nil || !updated {
+		t.Fatalf("first Update() = %v, %v; want true, nil", updated, err)
+	}
+
+	book, err := remote.Load(configPath, "team")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if book.Commands["docker"]["up"] != "docker compose up" {
+		t.Errorf("unexpected cached book: %v", book.Commands)
+	}
+
+	updated, err = remote.Update(server.Client(), configPath, "team", config.Remote{URL: url}, nil)
+	if err != nil || updated {
+		t.Fatalf("second Update() = %v, %v; want false, nil", updated, err)
+	}
+	if got := requests[1].Header.Get("If-Modified-Since"); got != "Mon, 02 Jan 2006 15:04:05 GMT" {
+		t.Errorf("If-Modified-Since = %q", got)
+	}
+
+	body = `
+[commands.docker]
+up = "docker compose up -d"
+`
+	etag = `"v2"`
+	updated, err = remote.Update(server.Client(), configPath, "team", config.Remote{URL: url}, nil)
+	if err != nil || !updated {
+		t.Fatalf("third Update() = %v, %v; want true, nil", updated, err)
+	}
+	if book, _ := remote.Load(configPath, "team"); book.Commands["docker"]["up"] != "docker compose up -d" {
+		t.Errorf("cache was not refreshed: %v", book.Commands)
+	}
+}
+
+func TestUpdateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "boom", http.StatusInternalServerError)
+			},
+		},
+		{
+			name: "invalid book",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("not = [valid"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(unsigned(tt.handler))
+			defer server.Close()
+
+			configPath := filepath.Join(t.TempDir(), "config.toml")
+			if _, err := remote.Update(server.Client(), configPath, "team", config.Remote{URL: server.URL}, nil); err == nil {
+				t.Fatal("expected an error")
+			}
+			if _, err := remote.Load(configPath, "team"); err != remote.ErrNotFetched {
+				t.Errorf("Load() error = %v, want %v", err, remote.ErrNotFetched)
+			}
+		})
+	}
+}
+
+func TestUpdateJSON(t *testing.T) {
+	server := httptest.NewServer(unsigned(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"commands": {"k8s": {"pods": "kubectl get pods"}}}`))
+	}))
+	defer server.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	if _, err := remote.Update(server.Client(), configPath, "team", config.Remote{URL: server.URL + "/book"}, nil); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	book, err := remote.Load(configPath, "team")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if book.Commands["k8s"]["pods"] != "kubectl get pods" {
+		t.Errorf("unexpected book: %v", book.Commands)
+	}
+}
+
+func TestWithRemotes(t *testing.T) {
+	server := httptest.NewServer(unsigned(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(teamBook))
+	}))
+	defer server.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	cfg := &config.Config{
+		Commands: map[string]map[string]string{"git": {"st": "git status"}},
+		Remotes: map[string]config.Remote{
+			"team":    {URL: server.URL},
+			"pending": {URL: server.URL},
+		},
+	}
+
+	if _, err := remote.Update(server.Client(), configPath, "team", config.Remote{URL: server.URL}, nil); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	view, err := remote.WithRemotes(configPath, cfg)
+	if err != nil {
+		t.Fatalf("WithRemotes() error = %v", err)
+	}
+
+	if view.Commands["git"]["st"] != "git status" {
+		t.Errorf("local entries missing: %v", view.Commands)
+	}
+	if view.Commands["team:docker"]["up"] != "docker compose up" {
+		t.Errorf("remote entries missing: %v", view.Commands)
+	}
+	if len(view.Commands) != 2 {
+		t.Errorf("unexpected prefixes: %v", view.Commands)
+	}
+	if _, leaked := cfg.Commands["team:docker"]; leaked {
+		t.Error("WithRemotes modified the original config")
+	}
+}
+
+func TestSplitNamespace(t *testing.T) {
+	tests := []struct {
+		prefix     string
+		wantName   string
+		wantPrefix string
+		wantOK     bool
+	}{
+		{prefix: "team:docker", wantName: "team", wantPrefix: "docker", wantOK: true},
+		{prefix: "docker", wantOK: false},
+		{prefix: ":docker", wantOK: false},
+		{prefix: "team:", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			name, prefix, ok := remote.SplitNamespace(tt.prefix)
+			if name != tt.wantName || prefix != tt.wantPrefix || ok != tt.wantOK {
+				t.Errorf("SplitNamespace(%q) = %q, %q, %v", tt.prefix, name, prefix, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/signature"
+)
+
+var (
+	ErrUnsigned   = errors.New("remote book is not signed")
+	ErrUntrusted  = errors.New("remote book is signed by an untrusted key")
+	ErrKeyChanged = errors.New("remote book is signed by a different key than the pinned one")
+)
+
+// Verify checks the cached book of remote name against its detached
+// signature. A signed book must be signed by the pinned key of the remote or
+// one of the trusted keys. An unsigned book is only accepted when the remote
+// neither pins a key nor requires signatures, which it does once it has
+// served a signed book.
+func Verify(configPath, name string, r config.Remote, trustedKeys map[string]string) error {
+	sig, data, err := loadSigned(configPath, name)
+	if err != nil {
+		return err
+	}
+
+	if sig == nil {
+		if r.Key != "" || r.RequireSignature || r.TrustOnFirstUse {
+			return ErrUnsigned
+		}
+		return nil
+	}
+
+	if err := sig.Verify(data); err != nil {
+		return err
+	}
+
+	key := signature.EncodeKey(sig.Key)
+	if r.Key != "" {
+		if key != r.Key {
+			return ErrKeyChanged
+		}
+		return nil
+	}
+
+	for _, trusted := range trustedKeys {
+		if trustedKey, err := signature.DecodeKey(trusted); err == nil && signature.EncodeKey(trustedKey) == key {
+			return nil
+		}
+	}
+	return ErrUntrusted
+}
+
+// Signed reports whether the cached book of remote name came with a
+// signature, valid or not.
+func Signed(configPath, name string) bool {
+	_, err := os.Stat(sigPath(configPath, name))
+	return err == nil
+}
+
+// FirstUseKey returns the key that signed the cached book of remote name,
+// after checking that the signature is valid, so it can be pinned.
+func FirstUseKey(configPath, name string) (string, error) {
+	sig, data, err := loadSigned(configPath, name)
+	if err != nil {
+		return "", err
+	}
+	if sig == nil {
+		return "", ErrUnsigned
+	}
+	if err := sig.Verify(data); err != nil {
+		return "", err
+	}
+	return signature.EncodeKey(sig.Key), nil
+}
+
+func loadSigned(configPath, name string) (*signature.Signature, []byte, error) {
+	data, err := os.ReadFile(bookPath(configPath, name))
+	if os.IsNotExist(err) {
+		return nil, nil, ErrNotFetched
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sigData, err := os.ReadFile(sigPath(configPath, name))
+	if os.IsNotExist(err) {
+		return nil, data, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sig, err := signature.Parse(sigData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid signature for remote %s: %w", name, err)
+	}
+	return sig, data, nil
+}
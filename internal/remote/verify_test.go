@@ -0,0 +1,175 @@
+package remote_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/remote"
+	"github.com/pHo9UBenaA/cmdbook/internal/signature"
+)
+
+func newKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return priv
+}
+
+func publicKey(priv ed25519.PrivateKey) string {
+	return signature.EncodeKey(priv.Public().(ed25519.PublicKey))
+}
+
+// signed serves teamBook with a signature over signedData made by priv.
+func signed(priv ed25519.PrivateKey, signedData string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, signature.Ext) {
+			_, _ = w.Write(signature.Sign(priv, []byte(signedData)).Marshal())
+			return
+		}
+		_, _ = w.Write([]byte(teamBook))
+	}
+}
+
+func TestVerify(t *testing.T) {
+	publisher := newKey(t)
+	stranger := newKey(t)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		remote  config.Remote
+		trusted map[string]string
+		wantErr error
+	}{
+		{
+			name:    "unsigned book without requirements",
+			handler: unsigned(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte(teamBook)) }),
+		},
+		{
+			name:    "unsigned book that must be signed",
+			handler: unsigned(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte(teamBook)) }),
+			remote:  config.Remote{RequireSignature: true},
+			wantErr: remote.ErrUnsigned,
+		},
+		{
+			name:    "signed by a trusted key",
+			handler: signed(publisher, teamBook),
+			trusted: map[string]string{"publisher": publicKey(publisher)},
+		},
+		{
+			name:    "signed by an untrusted key",
+			handler: signed(stranger, teamBook),
+			trusted: map[string]string{"publisher": publicKey(publisher)},
+			wantErr: remote.ErrUntrusted,
+		},
+		{
+			name:    "tampered book",
+			handler: signed(publisher, "[commands.docker]\nup = \"docker compose up\"\n"),
+			trusted: map[string]string{"publisher": publicKey(publisher)},
+			wantErr: signature.ErrInvalidSignature,
+		},
+		{
+			name:    "signed by the pinned key",
+			handler: signed(publisher, teamBook),
+			remote:  config.Remote{Key: publicKey(publisher)},
+		},
+		{
+			name:    "signed by a key other than the pinned one",
+			handler: signed(stranger, teamBook),
+			remote:  config.Remote{Key: publicKey(publisher)},
+			trusted: map[string]string{"stranger": publicKey(stranger)},
+			wantErr: remote.ErrKeyChanged,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			configPath := filepath.Join(t.TempDir(), "config.toml")
+			r := tt.remote
+			r.URL = server.URL + "/team.toml"
+
+			// A book failing verification never reaches the cache.
+			_, err := remote.Update(server.Client(), configPath, "team", r, tt.trusted)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if _, err := remote.Load(configPath, "team"); !errors.Is(err, remote.ErrNotFetched) {
+					t.Errorf("rejected book was cached: %v", err)
+				}
+				return
+			}
+
+			if err := remote.Verify(configPath, "team", r, tt.trusted); err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestFirstUseKey(t *testing.T) {
+	publisher := newKey(t)
+	server := httptest.NewServer(signed(publisher, teamBook))
+	defer server.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+
+	if _, err := remote.FirstUseKey(configPath, "team"); !errors.Is(err, remote.ErrNotFetched) {
+		t.Errorf("FirstUseKey() before update error = %v", err)
+	}
+
+	r := config.Remote{URL: server.URL + "/team.toml", TrustOnFirstUse: true}
+	if _, err := remote.Update(server.Client(), configPath, "team", r, nil); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	key, err := remote.FirstUseKey(configPath, "team")
+	if err != nil {
+		t.Fatalf("FirstUseKey() error = %v", err)
+	}
+	if key != publicKey(publisher) {
+		t.Errorf("FirstUseKey() = %q, want %q", key, publicKey(publisher))
+	}
+}
+
+func TestUpdateKeepsVerifiedCopy(t *testing.T) {
+	publisher := newKey(t)
+	handler := signed(publisher, teamBook)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { handler(w, r) }))
+	defer server.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	r := config.Remote{URL: server.URL + "/team.toml", Key: publicKey(publisher)}
+	if _, err := remote.Update(server.Client(), configPath, "team", r, nil); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	handler = unsigned(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("[commands.docker]\nup = \"echo pwned\"\n"))
+	})
+	if _, err := remote.Update(server.Client(), configPath, "team", r, nil); !errors.Is(err, remote.ErrUnsigned) {
+		t.Fatalf("Update() error = %v, want %v", err, remote.ErrUnsigned)
+	}
+
+	if err := remote.Verify(configPath, "team", r, nil); err != nil {
+		t.Errorf("the verified copy was replaced: %v", err)
+	}
+	if book, _ := remote.Load(configPath, "team"); book.Commands["docker"]["up"] != "docker compose up" {
+		t.Errorf("cached book = %v, want the verified one", book.Commands)
+	}
+	if staged, _ := filepath.Glob(filepath.Join(filepath.Dir(configPath), ".cmdbook", "remotes", "*.staged*")); len(staged) > 0 {
+		t.Errorf("staged files left behind: %v", staged)
+	}
+}
@@ -0,0 +1,116 @@
+package risk
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+)
+
+type Level int
+
+const (
+	Low Level = iota
+	Medium
+	High
+)
+
+var levelNames = map[Level]string{
+	Low:    "low",
+	Medium: "medium",
+	High:   "high",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+func ParseLevel(s string) (Level, error) {
+	for level, name := range levelNames {
+		if name == s {
+			return level, nil
+		}
+	}
+	return Low, fmt.Errorf("unknown risk level '%s': use low, medium or high", s)
+}
+
+type Assessment struct {
+	Level  Level
+	Reason string
+}
+
+type rule struct {
+	pattern *regexp.Regexp
+	level   Level
+	reason  string
+}
+
+// Arguments of rm, each with its leading space. The recursive and force
+// flags may be given together (-rf), apart and in either order, and long.
+const (
+	rmArg       = `\s+[^\s;&|]+`
+	rmRecursive = `\s+(-[a-zA-Z]*[rR][a-zA-Z]*|--recursive)`
+	rmForce     = `\s+(-[a-zA-Z]*f[a-zA-Z]*|--force)`
+	rmBoth      = `\s+-[a-zA-Z]*([rR][a-zA-Z]*f|f[a-zA-Z]*[rR])[a-zA-Z]*`
+	rmEnd       = `(\s|[;&|]|$)`
+)
+
+var builtinRules = []rule{
+	{regexp.MustCompile(`\brm(` + rmArg + `)*(` + rmBoth + `|` + rmRecursive + `(` + rmArg + `)*` + rmForce + `|` + rmForce + `(` + rmArg + `)*` + rmRecursive + `)` + rmEnd), High, "recursive forced delete"},
+	{regexp.MustCompile(`\bgit\s+push\b.*\s((--force|-f)(\s|$)|\+\S)`), High, "force push rewrites remote history"},
+	{regexp.MustCompile(`\bgit\s+reset\s+.*--hard\b`), High, "discards uncommitted changes"},
+	{regexp.MustCompile(`\bgit\s+clean\s+(\S+\s+)*-[a-zA-Z]*f`), High, "deletes untracked files"},
+	{regexp.MustCompile(`\bkubectl\s+(\S+\s+)*delete\b`), High, "deletes cluster resources"},
+	{regexp.MustCompile(`\bhelm\s+(uninstall|delete)\b`), High, "removes a release"},
+	{regexp.MustCompile(`\bterraform\s+(destroy|apply\s+.*-auto-approve)\b`), High, "changes infrastructure without review"},
+	{regexp.MustCompile(`(?i)\b(drop|truncate)\s+(table|database|schema)\b`), High, "destroys database objects"},
+	{regexp.MustCompile(`(?i)\bdelete\s+from\s+\S+\s*(;|$)`), High, "deletes every row of a table"},
+	{regexp.MustCompile(`\bmkfs(\.\w+)?\b`), High, "formats a filesystem"},
+	{regexp.MustCompile(`\bdd\s+.*\bof=/dev/`), High, "writes to a raw device"},
+	{regexp.MustCompile(`>\s*/dev/(sd|nvme|disk)`), High, "writes to a raw device"},
+	{regexp.MustCompile(`:\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`), High, "fork bomb"},
+	{regexp.MustCompile(`\b(curl|wget)\b.*\|\s*(sudo\s+)?(ba|z)?sh\b`), Medium, "pipes a download into a shell"},
+	{regexp.MustCompile(`\bdocker\s+(system|volume|image)\s+prune\b`), Medium, "deletes docker data"},
+	{regexp.MustCompile(`\bchmod\s+(-R\s+)?777\b`), Medium, "makes files world-writable"},
+	{regexp.MustCompile(`\bsudo\b`), Medium, "runs with elevated privileges"},
+}
+
+// Classify assesses command using the user rules first, in order, and then
+// the highest-risk matching built-in rule.
+func Classify(command string, userRules []config.RiskRule) (Assessment, error) {
+	for _, r := range userRules {
+		compiled, err := compileRule(r)
+		if err != nil {
+			return Assessment{}, err
+		}
+		if compiled.pattern.MatchString(command) {
+			return Assessment{Level: compiled.level, Reason: compiled.reason}, nil
+		}
+	}
+
+	assessment := Assessment{Level: Low}
+	for _, r := range builtinRules {
+		if r.level > assessment.Level && r.pattern.MatchString(command) {
+			assessment = Assessment{Level: r.level, Reason: r.reason}
+		}
+	}
+	return assessment, nil
+}
+
+func compileRule(r config.RiskRule) (rule, error) {
+	pattern, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return rule{}, fmt.Errorf("invalid risk rule pattern '%s': %w", r.Pattern, err)
+	}
+
+	level, err := ParseLevel(r.Level)
+	if err != nil {
+		return rule{}, err
+	}
+
+	reason := r.Reason
+	if reason == "" {
+		reason = "matches rule '" + r.Pattern + "'"
+	}
+	return rule{pattern: pattern, level: level, reason: reason}, nil
+}
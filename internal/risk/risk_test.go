@@ -0,0 +1,87 @@
+package risk_test
+
+import (
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/risk"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		rules   []config.RiskRule
+		want    risk.Level
+	}{
+		{name: "harmless command", command: "git status", want: risk.Low},
+		{name: "rm -rf", command: "rm -rf ./build", want: risk.High},
+		{name: "rm -fr with other flags", command: "rm -v -fr node_modules", want: risk.High},
+		{name: "rm -r -f", command: "rm -r -f /", want: risk.High},
+		{name: "rm -f -r", command: "rm -f -r /", want: risk.High},
+		{name: "rm with flags around a path", command: "rm -f ./build -R", want: risk.High},
+		{name: "rm --recursive --force", command: "rm --recursive --force /", want: risk.High},
+		{name: "rm without recursion", command: "rm -f out.log", want: risk.Low},
+		{name: "rm without force", command: "rm --recursive ./build", want: risk.Low},
+		{name: "rm with flags of another command", command: "rm -r ./build && ls -f", want: risk.Low},
+		{name: "git push --force", command: "git push --force origin main", want: risk.High},
+		{name: "git push -f", command: "git push -f", want: risk.High},
+		{name: "git push with refspec force", command: "git push origin +main", want: risk.High},
+		{name: "git push", command: "git push origin main", want: risk.Low},
+		{name: "git push --force-with-lease", command: "git push --force-with-lease", want: risk.Low},
+		{name: "git reset --hard", command: "git reset --hard HEAD~1", want: risk.High},
+		{name: "kubectl delete", command: "kubectl -n staging delete pod web-0", want: risk.High},
+		{name: "kubectl get", command: "kubectl get pods", want: risk.Low},
+		{name: "drop table", command: `psql -c "DROP TABLE users"`, want: risk.High},
+		{name: "delete without where", command: `psql -c "delete from users;"`, want: risk.High},
+		{name: "delete with where", command: `psql -c "delete from users where id = 1"`, want: risk.Low},
+		{name: "dd to device", command: "dd if=image.iso of=/dev/sdb bs=4M", want: risk.High},
+		{name: "curl pipe to shell", command: "curl -fsSL https://example.com/install | sh", want: risk.Medium},
+		{name: "sudo", command: "sudo apt update", want: risk.Medium},
+		{
+			name:    "user rule raises the level",
+			command: "helmfile sync",
+			rules:   []config.RiskRule{{Pattern: `^helmfile\s+sync`, Level: "high"}},
+			want:    risk.High,
+		},
+		{
+			name:    "user rule allows a built-in match",
+			command: "rm -rf ./dist",
+			rules:   []config.RiskRule{{Pattern: `^rm -rf \./dist$`, Level: "low"}},
+			want:    risk.Low,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := risk.Classify(tt.command, tt.rules)
+			if err != nil {
+				t.Fatalf("Classify() error = %v", err)
+			}
+			if got.Level != tt.want {
+				t.Errorf("Classify(%q) = %v (%s), want %v", tt.command, got.Level, got.Reason, tt.want)
+			}
+			if got.Level > risk.Low && got.Reason == "" {
+				t.Error("expected a reason for a risky command")
+			}
+		})
+	}
+}
+
+func TestClassifyInvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.RiskRule
+	}{
+		{name: "invalid pattern", rule: config.RiskRule{Pattern: "(", Level: "high"}},
+		{name: "invalid level", rule: config.RiskRule{Pattern: "x", Level: "extreme"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := risk.Classify("x", []config.RiskRule{tt.rule}); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
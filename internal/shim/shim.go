@@ -0,0 +1,208 @@
+// Package shim generates small executables that run entries of the book
+// through cb exec, so that they can be called like any other command.
+package shim
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+)
+
+const (
+	// NameSep joins the prefix and short of an entry in the name of its shim.
+	NameSep = "-"
+
+	// marker identifies the files written by cb, which are the only ones
+	// Sync overwrites or removes.
+	marker        = "# Generated by cb shim"
+	stateFileName = "shims.json"
+)
+
+var ErrNotInstalled = errors.New("no shims installed; run 'cb shim install --dir <dir>' first")
+
+// State records where the shims of a book are installed.
+type State struct {
+	Dir string `json:"dir"`
+}
+
+// Result lists the shims changed by Sync, by name.
+type Result struct {
+	Created []string
+	Updated []string
+	Removed []string
+	// Skipped maps entries that got no shim to the reason why.
+	Skipped map[string]string
+}
+
+// Name returns the name of the shim running ref, such as "git-push-main".
+func Name(ref domain.Ref) (string, error) {
+	name := ref.Prefix + NameSep + ref.Short
+	if !domain.ValidName(name) {
+		return "", fmt.Errorf("'%s' is not a usable file name", name)
+	}
+	return name, nil
+}
+
+// Script returns the shim running ref with cb, the path of the cb binary.
+// Its arguments are passed on to the command.
+func Script(cb string, ref domain.Ref) string {
+	return fmt.Sprintf("#!/bin/sh\n%s for %s %s; changes are overwritten.\nexec %s exec %s %s -- \"$@\"\n",
+		marker, ref.Prefix, ref.Short, quote(cb), quote(ref.Prefix), quote(ref.Short))
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Sync makes dir hold exactly one shim per ref: missing shims are created,
+// outdated ones rewritten and those of entries that no longer exist
+// removed. Files not written by cb are never touched, and entries named
+// like a program found elsewhere on $PATH get no shim.
+func Sync(dir, cb string, refs []domain.Ref) (Result, error) {
+	result := Result{Skipped: make(map[string]string)}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return result, fmt.Errorf("failed to create shim directory: %w", err)
+	}
+
+	refs = slices.Clone(refs)
+	sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+	wanted := make(map[string]domain.Ref, len(refs))
+	for _, ref := range refs {
+		name, err := Name(ref)
+		if err != nil {
+			result.Skipped[ref.Prefix+" "+ref.Short] = err.Error()
+			continue
+		}
+		if other, taken := wanted[name]; taken {
+			result.Skipped[ref.Prefix+" "+ref.Short] = fmt.Sprintf("%s is already used by %s %s", name, other.Prefix, other.Short)
+			continue
+		}
+		if path, found := lookPathElsewhere(name, dir); found {
+			result.Skipped[ref.Prefix+" "+ref.Short] = fmt.Sprintf("%s would shadow %s", name, path)
+			continue
+		}
+		wanted[name] = ref
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return result, fmt.Errorf("failed to read shim directory: %w", err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if _, ok := wanted[entry.Name()]; ok || !entry.Type().IsRegular() || !isShim(path) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return result, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		result.Removed = append(result.Removed, entry.Name())
+	}
+
+	for _, ref := range refs {
+		name, err := Name(ref)
+		if err != nil || wanted[name] != ref {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		script := Script(cb, ref)
+
+		current, err := os.ReadFile(path)
+		switch {
+		case err == nil && string(current) == script:
+			continue
+		case err == nil && !isShim(path):
+			result.Skipped[ref.Prefix+" "+ref.Short] = fmt.Sprintf("%s exists and was not written by cb", path)
+			continue
+		case err == nil:
+			result.Updated = append(result.Updated, name)
+		case errors.Is(err, os.ErrNotExist):
+			result.Created = append(result.Created, name)
+		default:
+			return result, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+			return result, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return result, nil
+}
+
+// lookPathElsewhere finds name in the directories of $PATH other than dir,
+// where a shim of the same name would shadow it or be shadowed by it.
+func lookPathElsewhere(name, dir string) (string, bool) {
+	dir, _ = filepath.Abs(dir)
+	for _, pathDir := range filepath.SplitList(os.Getenv("PATH")) {
+		if pathDir == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(pathDir); err != nil || abs == dir {
+			continue
+		}
+		if path, err := exec.LookPath(filepath.Join(pathDir, name)); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// isShim reports whether the file at path was written by cb.
+func isShim(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for i := 0; i < 2 && scanner.Scan(); i++ {
+		if strings.HasPrefix(scanner.Text(), marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func statePath(configPath string) string {
+	return filepath.Join(config.DataDir(configPath), stateFileName)
+}
+
+// LoadState returns where the shims of the book at configPath are
+// installed, or ErrNotInstalled.
+func LoadState(configPath string) (State, error) {
+	data, err := os.ReadFile(statePath(configPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return State{}, ErrNotInstalled
+	}
+	if err != nil {
+		return State{}, err
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return State{}, fmt.Errorf("invalid shim state: %w", err)
+	}
+	return state, nil
+}
+
+func SaveState(configPath string, state State) error {
+	if err := os.MkdirAll(config.DataDir(configPath), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(statePath(configPath), data, 0o644)
+}
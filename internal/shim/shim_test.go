@@ -0,0 +1,95 @@
+package shim_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/domain"
+	"github.com/pHo9UBenaA/cmdbook/internal/shim"
+)
+
+func TestSync(t *testing.T) {
+	dir := t.TempDir()
+	refs := []domain.Ref{
+		{Prefix: "git", Short: "push-main"},
+		{Prefix: "git", Short: "pull"},
+		{Prefix: "git-push", Short: "main"},
+		{Prefix: "docker", Short: "../up"},
+		{Prefix: "docker", Short: "compose"},
+	}
+
+	// A real docker-compose on $PATH keeps its name; the shim directory
+	// itself being on $PATH does not count.
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "docker-compose"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+bin)
+
+	if err := os.WriteFile(filepath.Join(dir, "git-pull"), []byte("#!/bin/sh\necho mine\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := shim.Sync(dir, "/usr/bin/cb", refs)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if !reflect.DeepEqual(result.Created, []string{"git-push-main"}) {
+		t.Errorf("Created = %v", result.Created)
+	}
+	// Of two entries with the same shim name, the first in order gets it.
+	wantSkipped := []string{"docker ../up", "docker compose", "git pull", "git push-main"}
+	for _, entry := range wantSkipped {
+		if _, ok := result.Skipped[entry]; !ok {
+			t.Errorf("%s should be skipped, got %v", entry, result.Skipped)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "git-push-main"))
+	if err != nil {
+		t.Fatalf("shim not written: %v", err)
+	}
+	if string(data) != shim.Script("/usr/bin/cb", refs[2]) {
+		t.Errorf("unexpected shim:\n%s", data)
+	}
+
+	// The entry was renamed and cb moved: the old shim goes, the new one
+	// is written and a file that is not a shim stays.
+	renamed := []domain.Ref{{Prefix: "git", Short: "pm"}}
+	result, err = shim.Sync(dir, "/opt/cb", renamed)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if !reflect.DeepEqual(result.Created, []string{"git-pm"}) || !reflect.DeepEqual(result.Removed, []string{"git-push-main"}) {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "git-pull")); err != nil {
+		t.Errorf("a file not written by cb was removed: %v", err)
+	}
+
+	result, err = shim.Sync(dir, "/usr/bin/cb", renamed)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if !reflect.DeepEqual(result.Updated, []string{"git-pm"}) || len(result.Created)+len(result.Removed) != 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestState(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+
+	if _, err := shim.LoadState(configPath); !errors.Is(err, shim.ErrNotInstalled) {
+		t.Fatalf("LoadState() error = %v, want ErrNotInstalled", err)
+	}
+	if err := shim.SaveState(configPath, shim.State{Dir: "/tmp/bin"}); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+	state, err := shim.LoadState(configPath)
+	if err != nil || state.Dir != "/tmp/bin" {
+		t.Errorf("LoadState() = %+v, %v", state, err)
+	}
+}
@@ -0,0 +1,91 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/config"
+)
+
+const (
+	keyDirName     = "keys"
+	privateKeyFile = "cmdbook_ed25519"
+	publicKeyExt   = ".pub"
+)
+
+func KeyDir(configPath string) string {
+	return filepath.Join(config.DataDir(configPath), keyDirName)
+}
+
+func PrivateKeyPath(configPath string) string {
+	return filepath.Join(KeyDir(configPath), privateKeyFile)
+}
+
+func PublicKeyPath(configPath string) string {
+	return PrivateKeyPath(configPath) + publicKeyExt
+}
+
+// GenerateKey creates a signing key pair in the key directory. An existing
+// key pair is only replaced when force is set.
+func GenerateKey(configPath string, force bool) (ed25519.PublicKey, error) {
+	privPath := PrivateKeyPath(configPath)
+	if _, err := os.Stat(privPath); err == nil && !force {
+		return nil, fmt.Errorf("signing key already exists: %s", privPath)
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(KeyDir(configPath), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(privPath, []byte(base64.StdEncoding.EncodeToString(priv)+"\n"), 0600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(PublicKeyPath(configPath), []byte(EncodeKey(pub)+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+func LoadPrivateKey(configPath string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(PrivateKeyPath(configPath))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no signing key; run 'cb keys gen' first")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(raw) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("malformed signing key: %s", PrivateKeyPath(configPath))
+	}
+	return ed25519.PrivateKey(raw), nil
+}
+
+// SignFile writes a detached signature of the book at path to path+Ext.
+func SignFile(configPath, path string) (string, error) {
+	priv, err := LoadPrivateKey(configPath)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	sigPath := path + Ext
+	if err := os.WriteFile(sigPath, Sign(priv, data).Marshal(), 0644); err != nil {
+		return "", err
+	}
+	return sigPath, nil
+}
@@ -0,0 +1,98 @@
+package signature
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	Ext = ".sig"
+
+	header    = "cmdbook-signature-v1"
+	keyField  = "key"
+	sigField  = "sig"
+	fieldSep  = ": "
+	keyPrefix = "ed25519:"
+)
+
+var ErrInvalidSignature = errors.New("signature does not match the book")
+
+// Signature is a detached ed25519 signature of a book together with the
+// public key that produced it.
+type Signature struct {
+	Key ed25519.PublicKey
+	Sig []byte
+}
+
+func Sign(priv ed25519.PrivateKey, data []byte) *Signature {
+	return &Signature{
+		Key: priv.Public().(ed25519.PublicKey),
+		Sig: ed25519.Sign(priv, data),
+	}
+}
+
+func (s *Signature) Verify(data []byte) error {
+	if !ed25519.Verify(s.Key, data, s.Sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (s *Signature) Marshal() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, header)
+	fmt.Fprintf(&buf, "%s%s%s\n", keyField, fieldSep, EncodeKey(s.Key))
+	fmt.Fprintf(&buf, "%s%s%s\n", sigField, fieldSep, base64.StdEncoding.EncodeToString(s.Sig))
+	return buf.Bytes()
+}
+
+func Parse(data []byte) (*Signature, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != header {
+		return nil, fmt.Errorf("not a cmdbook signature")
+	}
+
+	s := &Signature{}
+	for scanner.Scan() {
+		field, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), fieldSep)
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch field {
+		case keyField:
+			s.Key, err = DecodeKey(value)
+		case sigField:
+			s.Sig, err = base64.StdEncoding.DecodeString(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("malformed signature %s: %w", field, err)
+		}
+	}
+
+	if s.Key == nil || len(s.Sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("incomplete signature")
+	}
+	return s, nil
+}
+
+func EncodeKey(key ed25519.PublicKey) string {
+	return keyPrefix + base64.StdEncoding.EncodeToString(key)
+}
+
+func DecodeKey(s string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), keyPrefix))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 public key length %d", len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
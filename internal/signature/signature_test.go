@@ -0,0 +1,91 @@
+package signature_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pHo9UBenaA/cmdbook/internal/signature"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+
+	pub, err := signature.GenerateKey(configPath, false)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	if _, err := signature.GenerateKey(configPath, false); err == nil {
+		t.Error("expected an error when a key already exists")
+	}
+
+	info, err := os.Stat(signature.PrivateKeyPath(configPath))
+	if err != nil {
+		t.Fatalf("private key missing: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("private key permissions = %v, want 0600", info.Mode().Perm())
+	}
+
+	bookPath := filepath.Join(t.TempDir(), "team.toml")
+	book := []byte("[commands.docker]\nup = \"docker compose up\"\n")
+	if err := os.WriteFile(bookPath, book, 0644); err != nil {
+		t.Fatalf("failed to write book: %v", err)
+	}
+
+	sigPath, err := signature.SignFile(configPath, bookPath)
+	if err != nil {
+		t.Fatalf("SignFile() error = %v", err)
+	}
+	if sigPath != bookPath+signature.Ext {
+		t.Errorf("signature path = %q", sigPath)
+	}
+
+	data, err := os.ReadFile(sigPath)
+	if err != nil {
+		t.Fatalf("failed to read signature: %v", err)
+	}
+	sig, err := signature.Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if signature.EncodeKey(sig.Key) != signature.EncodeKey(pub) {
+		t.Errorf("signature key = %s, want %s", signature.EncodeKey(sig.Key), signature.EncodeKey(pub))
+	}
+	if err := sig.Verify(book); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if err := sig.Verify(append(book, '#')); err != signature.ErrInvalidSignature {
+		t.Errorf("Verify() on modified book error = %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "empty", data: ""},
+		{name: "wrong header", data: "hello\n"},
+		{name: "missing signature", data: "cmdbook-signature-v1\nkey: ed25519:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n"},
+		{name: "malformed key", data: "cmdbook-signature-v1\nkey: ed25519:%%%\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := signature.Parse([]byte(tt.data)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestDecodeKey(t *testing.T) {
+	if _, err := signature.DecodeKey("ed25519:c2hvcnQ="); err == nil {
+		t.Error("expected an error for a short key")
+	}
+	if _, err := signature.DecodeKey("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="); err != nil {
+		t.Errorf("DecodeKey() without prefix error = %v", err)
+	}
+}
@@ -2,7 +2,8 @@ package ioutil
 
 import (
 	"fmt"
-	"os"
+	"io"
+	"strings"
 
 	"github.com/pHo9UBenaA/cmdbook/internal/constant"
 	"github.com/pHo9UBenaA/cmdbook/internal/domain"
@@ -14,37 +15,64 @@ const (
 	AnsiCyan  = "\033[1;36m"
 	AnsiGreen = "\033[1;32m"
 	AnsiRed   = "\033[1;31m"
+	// AnsiReverse swaps the foreground and background colors.
+	AnsiReverse = "\033[7m"
 )
 
-func PrintInteractiveList(entries []domain.CommandEntry, pageSize, offset int) int {
-	width := getTerminalWidth()
+// PrintInteractiveList writes the page of entries starting at offset to w,
+// a terminal of the given width, highlighting the entry at selected (-1
+// for none). It returns the number of lines written.
+func PrintInteractiveList(w io.Writer, entries []domain.CommandEntry, width, pageSize, offset, selected int) int {
 	cmdWidth := width - constant.MaxShortLen - 4
 	printed := 0
 
 	for i := offset; i < len(entries) && printed < pageSize; i++ {
 		entry := entries[i]
 		if entry.Short == "" {
-			fmt.Printf("%s%s%s\n", AnsiCyan, entry.Prefix, AnsiReset)
+			fmt.Fprintf(w, "%s%s%s\n", AnsiCyan, entry.Prefix, AnsiReset)
 		} else {
 			short := entry.Short + ":"
-			cmd := truncateString(entry.Command, cmdWidth)
-			fmt.Printf("  %s%-*s%s %-*s\n",
-				AnsiGreen, constant.MaxShortLen, short, AnsiReset,
-				cmdWidth, cmd)
+			cmd := truncateString(FirstLine(entry.Command), cmdWidth)
+			line := fmt.Sprintf("%s%-*s%s %-*s", AnsiGreen, constant.MaxShortLen, short, AnsiReset, cmdWidth, cmd)
+			if i == selected {
+				line = AnsiReverse + stripAnsi(line) + AnsiReset
+			}
+			fmt.Fprintf(w, "%s %s\n", riskMarker(entry), line)
 		}
 		printed++
 	}
 	return printed
 }
 
-func getTerminalWidth() int {
-	width, _, _ := term.GetSize(int(os.Stdout.Fd()))
+func stripAnsi(s string) string {
+	return strings.NewReplacer(AnsiGreen, "", AnsiReset, "").Replace(s)
+}
+
+func riskMarker(entry domain.CommandEntry) string {
+	if entry.HighRisk {
+		return AnsiRed + "!" + AnsiReset
+	}
+	return " "
+}
+
+// TerminalWidth returns the width of the terminal open as fd, or 80 when it
+// cannot be determined or is too narrow to be useful.
+func TerminalWidth(fd int) int {
+	width, _, _ := term.GetSize(fd)
 	if width < 40 {
 		return 80
 	}
 	return width
 }
 
+// FirstLine shortens multi-line scripts to their first line.
+func FirstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i] + " ..."
+	}
+	return s
+}
+
 func truncateString(s string, max int) string {
 	if len(s) > max {
 		return s[:max-3] + "..."